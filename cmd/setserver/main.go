@@ -0,0 +1,53 @@
+// Command setserver serves named sets over the Redis protocol, so any Redis client can use them:
+//
+//	setserver -addr :6380 &
+//	redis-cli -p 6380 SADD admins ann bob
+//
+// It shuts down gracefully on SIGINT or SIGTERM, answering the commands already received.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	set "github.com/fanjindong/go-set"
+)
+
+func main() {
+	addr := flag.String("addr", ":6380", "TCP address to listen on")
+	sweep := flag.Duration("sweep", time.Minute, "interval between deletions of the expired keys, 0 to disable")
+	grace := flag.Duration("grace", 10*time.Second, "time given to the connections to finish on shutdown")
+	flag.Parse()
+
+	st := set.NewStore(nil)
+	if *sweep > 0 {
+		go func() {
+			for range time.Tick(*sweep) {
+				st.Sweep()
+			}
+		}()
+	}
+	srv := set.NewServer(st)
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe(*addr) }()
+	log.Printf("setserver listening on %s", *addr)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errc:
+		log.Fatal(err)
+	case s := <-sig:
+		log.Printf("setserver shutting down on %v", s)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *grace)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,304 @@
+package set
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Element encoding tags. The encoding is part of the on-disk formats, never renumber them.
+const (
+	tagNil byte = iota
+	tagFalse
+	tagTrue
+	tagInt
+	tagInt8
+	tagInt16
+	tagInt32
+	tagInt64
+	tagUint
+	tagUint8
+	tagUint16
+	tagUint32
+	tagUint64
+	tagFloat32
+	tagFloat64
+	tagComplex64
+	tagComplex128
+	tagString
+	tagFrozen
+)
+
+var errShortElem = errors.New("go-set: decode err, unexpected end of element")
+
+// appendElem appends the binary encoding of elem to b.
+// nil, booleans, numbers, strings and Frozen sets of them can be encoded, other types return an error.
+func appendElem(b []byte, elem interface{}) ([]byte, error) {
+	switch x := elem.(type) {
+	case nil:
+		return append(b, tagNil), nil
+	case bool:
+		if x {
+			return append(b, tagTrue), nil
+		}
+		return append(b, tagFalse), nil
+	case int:
+		return appendVarint(append(b, tagInt), int64(x)), nil
+	case int8:
+		return appendVarint(append(b, tagInt8), int64(x)), nil
+	case int16:
+		return appendVarint(append(b, tagInt16), int64(x)), nil
+	case int32:
+		return appendVarint(append(b, tagInt32), int64(x)), nil
+	case int64:
+		return appendVarint(append(b, tagInt64), x), nil
+	case uint:
+		return appendUvarint(append(b, tagUint), uint64(x)), nil
+	case uint8:
+		return appendUvarint(append(b, tagUint8), uint64(x)), nil
+	case uint16:
+		return appendUvarint(append(b, tagUint16), uint64(x)), nil
+	case uint32:
+		return appendUvarint(append(b, tagUint32), uint64(x)), nil
+	case uint64:
+		return appendUvarint(append(b, tagUint64), x), nil
+	case float32:
+		return appendUint32(append(b, tagFloat32), math.Float32bits(x)), nil
+	case float64:
+		return appendUint64(append(b, tagFloat64), math.Float64bits(x)), nil
+	case complex64:
+		b = appendUint32(append(b, tagComplex64), math.Float32bits(real(x)))
+		return appendUint32(b, math.Float32bits(imag(x))), nil
+	case complex128:
+		b = appendUint64(append(b, tagComplex128), math.Float64bits(real(x)))
+		return appendUint64(b, math.Float64bits(imag(x))), nil
+	case string:
+		b = appendUvarint(append(b, tagString), uint64(len(x)))
+		return append(b, x...), nil
+	case Frozen:
+		b = appendUvarint(append(b, tagFrozen), uint64(x.n))
+		b = appendUvarint(b, uint64(len(x.enc)))
+		return append(b, x.enc...), nil
+	}
+	return b, fmt.Errorf("go-set: encode err, unsupported element type %T", elem)
+}
+
+func appendVarint(b []byte, x int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(b, tmp[:binary.PutVarint(tmp[:], x)]...)
+}
+
+func appendUvarint(b []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(b, tmp[:binary.PutUvarint(tmp[:], x)]...)
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], x)
+	return append(b, tmp[:]...)
+}
+
+func appendUint64(b []byte, x uint64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], x)
+	return append(b, tmp[:]...)
+}
+
+// decodeElem decodes the element at the front of b, it returns the element and the number of bytes read.
+func decodeElem(b []byte) (interface{}, int, error) {
+	if len(b) == 0 {
+		return nil, 0, errShortElem
+	}
+	tag, p := b[0], b[1:]
+	switch tag {
+	case tagNil:
+		return nil, 1, nil
+	case tagFalse:
+		return false, 1, nil
+	case tagTrue:
+		return true, 1, nil
+	case tagInt, tagInt8, tagInt16, tagInt32, tagInt64:
+		x, n := binary.Varint(p)
+		if n <= 0 {
+			return nil, 0, errShortElem
+		}
+		var elem interface{}
+		switch tag {
+		case tagInt:
+			elem = int(x)
+		case tagInt8:
+			elem = int8(x)
+		case tagInt16:
+			elem = int16(x)
+		case tagInt32:
+			elem = int32(x)
+		default:
+			elem = x
+		}
+		return elem, 1 + n, nil
+	case tagUint, tagUint8, tagUint16, tagUint32, tagUint64:
+		x, n := binary.Uvarint(p)
+		if n <= 0 {
+			return nil, 0, errShortElem
+		}
+		var elem interface{}
+		switch tag {
+		case tagUint:
+			elem = uint(x)
+		case tagUint8:
+			elem = uint8(x)
+		case tagUint16:
+			elem = uint16(x)
+		case tagUint32:
+			elem = uint32(x)
+		default:
+			elem = x
+		}
+		return elem, 1 + n, nil
+	case tagFloat32:
+		if len(p) < 4 {
+			return nil, 0, errShortElem
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(p)), 5, nil
+	case tagFloat64:
+		if len(p) < 8 {
+			return nil, 0, errShortElem
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(p)), 9, nil
+	case tagComplex64:
+		if len(p) < 8 {
+			return nil, 0, errShortElem
+		}
+		re := math.Float32frombits(binary.LittleEndian.Uint32(p))
+		im := math.Float32frombits(binary.LittleEndian.Uint32(p[4:]))
+		return complex(re, im), 9, nil
+	case tagComplex128:
+		if len(p) < 16 {
+			return nil, 0, errShortElem
+		}
+		re := math.Float64frombits(binary.LittleEndian.Uint64(p))
+		im := math.Float64frombits(binary.LittleEndian.Uint64(p[8:]))
+		return complex(re, im), 17, nil
+	case tagString:
+		l, n := binary.Uvarint(p)
+		if n <= 0 || uint64(len(p)-n) < l {
+			return nil, 0, errShortElem
+		}
+		return string(p[n : n+int(l)]), 1 + n + int(l), nil
+	case tagFrozen:
+		count, n := binary.Uvarint(p)
+		if n <= 0 {
+			return nil, 0, errShortElem
+		}
+		l, m := binary.Uvarint(p[n:])
+		if m <= 0 || uint64(len(p)-n-m) < l {
+			return nil, 0, errShortElem
+		}
+		f, err := frozenFromEncoding(string(p[n+m:n+m+int(l)]), count)
+		if err != nil {
+			return nil, 0, err
+		}
+		return f, 1 + n + m + int(l), nil
+	}
+	return nil, 0, fmt.Errorf("go-set: decode err, unknown element tag %d", tag)
+}
+
+// WriteSet writes the elements of s to w in the package's binary format, see ReadSet.
+// nil, booleans, numbers, strings and Frozen sets can be written, other element types return an error.
+func WriteSet(w io.Writer, s ISet) error {
+	elems := s.ToSlice().Interface()
+	bw := bufio.NewWriter(w)
+	var buf []byte
+	buf = appendUvarint(buf, uint64(len(elems)))
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+	for _, elem := range elems {
+		if err := writeElem(bw, elem, buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeElem writes a length prefixed element, reusing buf as scratch space.
+func writeElem(w io.Writer, elem interface{}, buf []byte) error {
+	enc, err := appendElem(buf[:0], elem)
+	if err != nil {
+		return err
+	}
+	var prefix [binary.MaxVarintLen64]byte
+	if _, err := w.Write(prefix[:binary.PutUvarint(prefix[:], uint64(len(enc)))]); err != nil {
+		return err
+	}
+	_, err = w.Write(enc)
+	return err
+}
+
+// ReadSet reads elements written by WriteSet from r and adds them to s.
+// If r is not an io.ByteReader, ReadSet may read past the end of the set.
+// Examples:
+// var buf bytes.Buffer
+// WriteSet(&buf, NewSet(1, "a"))
+// s := NewSet()
+// ReadSet(&buf, s) // s={1,a}
+func ReadSet(r io.Reader, s ISet) error {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return err
+	}
+	var buf []byte
+	for i := uint64(0); i < n; i++ {
+		var elem interface{}
+		if elem, buf, err = readElem(br, buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		s.Adds(elem)
+	}
+	return nil
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// maxElemSize bounds the length prefix of an encoded element, so corrupt input cannot trigger huge allocations.
+const maxElemSize = 1 << 30
+
+// readElem reads a length prefixed element, reusing buf as scratch space.
+func readElem(r byteReader, buf []byte) (interface{}, []byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, buf, err
+	}
+	if l > maxElemSize {
+		return nil, buf, fmt.Errorf("go-set: decode err, element of %d bytes is too large", l)
+	}
+	if uint64(cap(buf)) < l {
+		buf = make([]byte, l)
+	}
+	buf = buf[:l]
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, buf, err
+	}
+	elem, n, err := decodeElem(buf)
+	if err == nil && n != len(buf) {
+		err = fmt.Errorf("go-set: decode err, %d trailing bytes", len(buf)-n)
+	}
+	return elem, buf, err
+}
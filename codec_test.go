@@ -0,0 +1,51 @@
+package set
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestWriteSet_ReadSet(t *testing.T) {
+	tests := []struct {
+		name string
+		s    ISet
+	}{
+		{name: "empty", s: NewSet()},
+		{name: "ints", s: NewSet(-1, int8(-2), int16(3), int32(-4), int64(1<<40))},
+		{name: "uints", s: NewSet(uint(1), uint8(2), uint16(3), uint32(4), uint64(1<<63))},
+		{name: "floats", s: NewSet(float32(1.5), -2.25, complex64(1+2i), complex(3, -4))},
+		{name: "others", s: NewSet(nil, true, false, "", "a,b{c}")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteSet(&buf, tt.s); err != nil {
+				t.Fatalf("WriteSet() error = %v", err)
+			}
+			got := NewThreadUnsafeSet()
+			if err := ReadSet(&buf, got); err != nil {
+				t.Fatalf("ReadSet() error = %v", err)
+			}
+			if !got.Equal(tt.s) {
+				t.Errorf("ReadSet() = %v, want %v", got, tt.s)
+			}
+		})
+	}
+}
+
+func TestWriteSet_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSet(&buf, NewSet(struct{}{})); err == nil {
+		t.Errorf("WriteSet() error = %v, want an error", err)
+	}
+	buf.Reset()
+	WriteSet(&buf, NewSet("abc", 1))
+	truncated := buf.Bytes()[:buf.Len()-2]
+	if err := ReadSet(bytes.NewReader(truncated), NewSet()); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadSet() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if err := ReadSet(bytes.NewReader([]byte{1, 1, 99}), NewSet()); err == nil {
+		t.Errorf("ReadSet() unknown tag error = %v, want an error", err)
+	}
+}
@@ -0,0 +1,301 @@
+package set
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// InvalidElem is an element a coercing conversion rejected, see ConversionError.
+type InvalidElem struct {
+	Index int
+	Value interface{}
+}
+
+// ConversionError lists every element a coercing conversion such as AsInt rejected.
+type ConversionError struct {
+	// Method is the conversion, such as "AsInt".
+	Method string
+	// Invalid lists the rejected elements in order.
+	Invalid []InvalidElem
+}
+
+func (e *ConversionError) Error() string {
+	elems := make([]string, 0, len(e.Invalid))
+	for _, x := range e.Invalid {
+		elems = append(elems, fmt.Sprintf("[%d]=%#v", x.Index, x.Value))
+	}
+	return fmt.Sprintf("go-set: Slice %s() err, %d invalid values: %s", e.Method, len(e.Invalid), strings.Join(elems, ", "))
+}
+
+// conversion collects the elements a conversion rejects.
+type conversion struct {
+	method  string
+	invalid []InvalidElem
+}
+
+func (c *conversion) reject(i int, v interface{}) {
+	c.invalid = append(c.invalid, InvalidElem{Index: i, Value: v})
+}
+
+func (c *conversion) err() error {
+	if len(c.invalid) == 0 {
+		return nil
+	}
+	return &ConversionError{Method: c.method, Invalid: c.invalid}
+}
+
+// coerceInt64 is exactInt64 that also parses json.Number.
+func coerceInt64(v interface{}) (int64, bool) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return 0, false
+		}
+		return exactInt64(f)
+	}
+	return exactInt64(v)
+}
+
+// coerceUint64 is exactUint64 that also parses json.Number.
+func coerceUint64(v interface{}) (uint64, bool) {
+	if n, ok := v.(json.Number); ok {
+		if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+			return u, true
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return 0, false
+		}
+		return exactUint64(f)
+	}
+	return exactUint64(v)
+}
+
+// coerceFloat64 is exactFloat64 that also parses json.Number.
+func coerceFloat64(v interface{}) (float64, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return exactFloat64(v)
+}
+
+func asInts(method string, s []interface{}, min, max int64) ([]int64, error) {
+	c := conversion{method: method}
+	result := make([]int64, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		v, ok := coerceInt64(s[i])
+		if !ok || v < min || v > max {
+			c.reject(i, s[i])
+			continue
+		}
+		result = append(result, v)
+	}
+	return result, c.err()
+}
+
+func asUints(method string, s []interface{}, max uint64) ([]uint64, error) {
+	c := conversion{method: method}
+	result := make([]uint64, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		v, ok := coerceUint64(s[i])
+		if !ok || v > max {
+			c.reject(i, s[i])
+			continue
+		}
+		result = append(result, v)
+	}
+	return result, c.err()
+}
+
+// AsInt converts the elements to int: integers and integral floats of any type, and json.Number,
+// as long as they fit. The error is a *ConversionError listing every element that does not.
+func (s Slice) AsInt() ([]int, error) {
+	vs, err := asInts("AsInt", s, -1<<(strconv.IntSize-1), 1<<(strconv.IntSize-1)-1)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int, len(vs))
+	for i, v := range vs {
+		result[i] = int(v)
+	}
+	return result, nil
+}
+
+// AsInt8 converts the elements to int8, see AsInt.
+func (s Slice) AsInt8() ([]int8, error) {
+	vs, err := asInts("AsInt8", s, math.MinInt8, math.MaxInt8)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int8, len(vs))
+	for i, v := range vs {
+		result[i] = int8(v)
+	}
+	return result, nil
+}
+
+// AsInt16 converts the elements to int16, see AsInt.
+func (s Slice) AsInt16() ([]int16, error) {
+	vs, err := asInts("AsInt16", s, math.MinInt16, math.MaxInt16)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int16, len(vs))
+	for i, v := range vs {
+		result[i] = int16(v)
+	}
+	return result, nil
+}
+
+// AsInt32 converts the elements to int32, see AsInt.
+func (s Slice) AsInt32() ([]int32, error) {
+	vs, err := asInts("AsInt32", s, math.MinInt32, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int32, len(vs))
+	for i, v := range vs {
+		result[i] = int32(v)
+	}
+	return result, nil
+}
+
+// AsInt64 converts the elements to int64, see AsInt.
+func (s Slice) AsInt64() ([]int64, error) {
+	vs, err := asInts("AsInt64", s, math.MinInt64, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// AsUint converts the elements to uint, see AsInt.
+func (s Slice) AsUint() ([]uint, error) {
+	vs, err := asUints("AsUint", s, 1<<strconv.IntSize-1)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint, len(vs))
+	for i, v := range vs {
+		result[i] = uint(v)
+	}
+	return result, nil
+}
+
+// AsUint8 converts the elements to uint8, see AsInt.
+func (s Slice) AsUint8() ([]uint8, error) {
+	vs, err := asUints("AsUint8", s, math.MaxUint8)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint8, len(vs))
+	for i, v := range vs {
+		result[i] = uint8(v)
+	}
+	return result, nil
+}
+
+// AsUint16 converts the elements to uint16, see AsInt.
+func (s Slice) AsUint16() ([]uint16, error) {
+	vs, err := asUints("AsUint16", s, math.MaxUint16)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint16, len(vs))
+	for i, v := range vs {
+		result[i] = uint16(v)
+	}
+	return result, nil
+}
+
+// AsUint32 converts the elements to uint32, see AsInt.
+func (s Slice) AsUint32() ([]uint32, error) {
+	vs, err := asUints("AsUint32", s, math.MaxUint32)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint32, len(vs))
+	for i, v := range vs {
+		result[i] = uint32(v)
+	}
+	return result, nil
+}
+
+// AsUint64 converts the elements to uint64, see AsInt.
+func (s Slice) AsUint64() ([]uint64, error) {
+	vs, err := asUints("AsUint64", s, math.MaxUint64)
+	if err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// AsFloat32 converts the elements to float32: numbers of any type, and json.Number,
+// as long as float32 holds their value exactly. See AsInt for the error.
+func (s Slice) AsFloat32() ([]float32, error) {
+	c := conversion{method: "AsFloat32"}
+	result := make([]float32, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		v, ok := coerceFloat64(s[i])
+		if f := float32(v); ok && (float64(f) == v || math.IsNaN(v)) {
+			result = append(result, f)
+			continue
+		}
+		c.reject(i, s[i])
+	}
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AsFloat64 converts the elements to float64: numbers of any type, and json.Number,
+// as long as float64 holds their value exactly. See AsInt for the error.
+func (s Slice) AsFloat64() ([]float64, error) {
+	c := conversion{method: "AsFloat64"}
+	result := make([]float64, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		v, ok := coerceFloat64(s[i])
+		if !ok {
+			c.reject(i, s[i])
+			continue
+		}
+		result = append(result, v)
+	}
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AsString converts the elements to string: strings, byte slices, and values implementing fmt.Stringer
+// or error. See AsInt for the error.
+func (s Slice) AsString() ([]string, error) {
+	c := conversion{method: "AsString"}
+	result := make([]string, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch v := s[i].(type) {
+		case string:
+			result = append(result, v)
+		case []byte:
+			result = append(result, string(v))
+		case fmt.Stringer:
+			result = append(result, v.String())
+		case error:
+			result = append(result, v.Error())
+		default:
+			c.reject(i, s[i])
+		}
+	}
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package set
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSlice_AsInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       Slice
+		want    []int
+		invalid []int
+	}{
+		{name: "empty", s: Slice{}, want: []int{}},
+		{name: "widening", s: Slice{int8(1), int32(2), uint16(3), int64(4)}, want: []int{1, 2, 3, 4}},
+		{name: "floats", s: Slice{1.0, float32(2)}, want: []int{1, 2}},
+		{name: "json.Number", s: Slice{json.Number("1"), json.Number("2e3")}, want: []int{1, 2000}},
+		{name: "invalid", s: Slice{1, 1.5, "a", json.Number("x"), nil, uint64(math.MaxUint64)}, invalid: []int{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.s.AsInt()
+			var ce *ConversionError
+			if tt.invalid != nil {
+				if !errors.As(err, &ce) || len(ce.Invalid) != len(tt.invalid) {
+					t.Fatalf("AsInt() error = %v, want %v invalid values", err, len(tt.invalid))
+				}
+				for i, x := range ce.Invalid {
+					if x.Index != tt.invalid[i] || !reflect.DeepEqual(x.Value, tt.s[x.Index]) {
+						t.Errorf("AsInt() invalid %v = %v, want index %v", i, x, tt.invalid[i])
+					}
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AsInt() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlice_AsNarrowing(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(Slice) (interface{}, error)
+		ok   Slice
+		want interface{}
+		bad  Slice
+	}{
+		{name: "AsInt8", fn: func(s Slice) (interface{}, error) { return s.AsInt8() }, ok: Slice{-128, 127}, want: []int8{-128, 127}, bad: Slice{128, -129}},
+		{name: "AsInt16", fn: func(s Slice) (interface{}, error) { return s.AsInt16() }, ok: Slice{int64(-300)}, want: []int16{-300}, bad: Slice{1 << 15}},
+		{name: "AsInt32", fn: func(s Slice) (interface{}, error) { return s.AsInt32() }, ok: Slice{uint32(7)}, want: []int32{7}, bad: Slice{uint32(math.MaxUint32)}},
+		{name: "AsInt64", fn: func(s Slice) (interface{}, error) { return s.AsInt64() }, ok: Slice{uint(7)}, want: []int64{7}, bad: Slice{1e19}},
+		{name: "AsUint", fn: func(s Slice) (interface{}, error) { return s.AsUint() }, ok: Slice{int8(7)}, want: []uint{7}, bad: Slice{-1}},
+		{name: "AsUint8", fn: func(s Slice) (interface{}, error) { return s.AsUint8() }, ok: Slice{255}, want: []uint8{255}, bad: Slice{256}},
+		{name: "AsUint16", fn: func(s Slice) (interface{}, error) { return s.AsUint16() }, ok: Slice{json.Number("65535")}, want: []uint16{65535}, bad: Slice{65536}},
+		{name: "AsUint32", fn: func(s Slice) (interface{}, error) { return s.AsUint32() }, ok: Slice{1.0}, want: []uint32{1}, bad: Slice{1 << 32}},
+		{name: "AsUint64", fn: func(s Slice) (interface{}, error) { return s.AsUint64() }, ok: Slice{json.Number("18446744073709551615")}, want: []uint64{math.MaxUint64}, bad: Slice{-1.0}},
+		{name: "AsFloat32", fn: func(s Slice) (interface{}, error) { return s.AsFloat32() }, ok: Slice{1, 0.5}, want: []float32{1, 0.5}, bad: Slice{0.1, 1<<24 + 1}},
+		{name: "AsFloat64", fn: func(s Slice) (interface{}, error) { return s.AsFloat64() }, ok: Slice{float32(0.5), 2, json.Number("0.1")}, want: []float64{0.5, 2, 0.1}, bad: Slice{int64(1<<53 + 1), complex(1, 1)}},
+		{name: "AsString", fn: func(s Slice) (interface{}, error) { return s.AsString() }, ok: Slice{"a", []byte("b"), time.Second, errors.New("c")}, want: []string{"a", "b", "1s", "c"}, bad: Slice{1, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.fn(tt.ok); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s() = %v, %v, want %v", tt.name, got, err, tt.want)
+			}
+			var ce *ConversionError
+			if _, err := tt.fn(tt.bad); !errors.As(err, &ce) || len(ce.Invalid) != len(tt.bad) || ce.Method != tt.name {
+				t.Errorf("%s() error = %v, want every value of %v invalid", tt.name, err, tt.bad)
+			}
+		})
+	}
+}
+
+func TestConversionError(t *testing.T) {
+	_, err := NewSet(1).ToSlice().AsString()
+	if want := "go-set: Slice AsString() err, 1 invalid values: [0]=1"; err == nil || err.Error() != want {
+		t.Errorf("AsString() error = %v, want %v", err, want)
+	}
+}
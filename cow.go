@@ -0,0 +1,160 @@
+package set
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// NewCOWSet returns a copy-on-write set tuned for read-mostly workloads.
+// Readers load the current version with a single atomic operation and never block,
+// writers clone the current version, mutate the clone and publish it atomically.
+// Use Update to apply many mutations at the cost of a single copy.
+func NewCOWSet(elems ...interface{}) *COWSet {
+	s := &COWSet{}
+	s.v.Store(NewThreadUnsafeSet(elems...).(*threadUnsafeSet))
+	return s
+}
+
+// COWSet is a copy-on-write ISet. The published version is never mutated in place,
+// so any number of goroutines may read it while a writer prepares the next one.
+type COWSet struct {
+	v  atomic.Value // *threadUnsafeSet
+	mu sync.Mutex   // serializes writers
+}
+
+func (s *COWSet) load() *threadUnsafeSet {
+	return s.v.Load().(*threadUnsafeSet)
+}
+
+// write clones the current version, applies fn to the clone and publishes the result.
+func (s *COWSet) write(fn func(m *threadUnsafeSet)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.load().Clone().(*threadUnsafeSet)
+	fn(m)
+	s.v.Store(m)
+}
+
+// Update applies fn to a private copy of the set and publishes it once fn returns,
+// so a bulk update costs one copy however many elements it touches.
+// Readers observe either the state before Update or the state after it, never a partial one.
+// The ISet passed to fn must not be retained after fn returns.
+// Examples:
+// s.Update(func(m ISet) { m.Removes(1, 2); m.Adds(3, 4) })
+func (s *COWSet) Update(fn func(ISet)) {
+	s.write(func(m *threadUnsafeSet) { fn(m) })
+}
+
+// Replace atomically replaces the contents of the set with elems.
+// Examples:
+// {1, 2}.Replace(3, 4)={3,4}
+func (s *COWSet) Replace(elems ...interface{}) {
+	m := NewThreadUnsafeSet(elems...).(*threadUnsafeSet)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.v.Store(m)
+}
+
+// Snapshot returns a read-only view of the currently published version, see ISnapshotter.
+// Published versions are never mutated, so this costs no copy at all.
+func (s *COWSet) Snapshot() ISet {
+	return &snapshotSet{m: s.load()}
+}
+
+// Restore publishes the contents of a snapshot, see ISnapshotter.
+func (s *COWSet) Restore(snapshot ISet) error {
+	snap, ok := snapshot.(*snapshotSet)
+	if !ok {
+		return ErrNotSnapshot
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.v.Store(snap.m)
+	return nil
+}
+
+func (s *COWSet) Empty() bool {
+	return s.Cardinality() == 0
+}
+
+func (s *COWSet) Singleton() bool {
+	return s.Cardinality() == 1
+}
+
+func (s *COWSet) Cardinality() int {
+	return s.load().Cardinality()
+}
+
+func (s *COWSet) ToSlice() ISlice {
+	return s.load().ToSlice()
+}
+
+func (s *COWSet) Adds(elems ...interface{}) bool {
+	if s.load().Contains(elems...) {
+		return len(elems) == 0
+	}
+	var added bool
+	s.write(func(m *threadUnsafeSet) { added = m.Adds(elems...) })
+	return added
+}
+
+func (s *COWSet) Removes(elems ...interface{}) bool {
+	var removed bool
+	s.write(func(m *threadUnsafeSet) { removed = m.Removes(elems...) })
+	return removed
+}
+
+func (s *COWSet) IsSub(other ISet) bool {
+	return s.load().IsSub(other)
+}
+
+func (s *COWSet) Unions(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Adds(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *COWSet) Intersections(others ...ISet) ISet {
+	return NewCOWSet(s.load().Intersections(others...).ToSlice().Interface()...)
+}
+
+func (s *COWSet) Complements(others ...ISet) ISet {
+	return NewCOWSet(s.load().Complements(others...).ToSlice().Interface()...)
+}
+
+func (s *COWSet) Clear() {
+	s.Replace()
+}
+
+func (s *COWSet) Contains(elems ...interface{}) bool {
+	return s.load().Contains(elems...)
+}
+
+func (s *COWSet) Clone() ISet {
+	return NewCOWSet(s.ToSlice().Interface()...)
+}
+
+func (s *COWSet) Equal(other ISet) bool {
+	return s.load().Equal(other)
+}
+
+func (s *COWSet) Pop() interface{} {
+	var elem interface{}
+	s.write(func(m *threadUnsafeSet) { elem = m.Pop() })
+	return elem
+}
+
+func (s *COWSet) String() string {
+	return s.load().String()
+}
+
+func (s *COWSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", s)
+}
+
+func (s *COWSet) Hash() uint64 {
+	return s.load().Hash()
+}
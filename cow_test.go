@@ -0,0 +1,142 @@
+package set
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCOWSet_Adds(t *testing.T) {
+	s := NewCOWSet()
+	wg := sync.WaitGroup{}
+	for i := range elems {
+		wg.Add(2)
+		go func(i int) {
+			s.Adds(elems[i])
+			wg.Done()
+		}(i)
+		go func(i int) {
+			s.Contains(elems[i])
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	if s.Cardinality() != len(elems) {
+		t.Errorf("Adds.Cardinality() = %v, want %v", s.Cardinality(), len(elems))
+	}
+	if s.Adds(elems[0]) {
+		t.Errorf("Adds() = %v, want %v", true, false)
+	}
+}
+
+func TestCOWSet_Update(t *testing.T) {
+	s := NewCOWSet(1, 2, 3)
+	before := s.load()
+	s.Update(func(m ISet) {
+		m.Removes(1)
+		m.Adds(4, 5)
+	})
+	if want := NewSet(2, 3, 4, 5); !s.Equal(want) {
+		t.Errorf("Update() = %v, want %v", s, want)
+	}
+	if want := NewSet(1, 2, 3); !before.Equal(want) {
+		t.Errorf("Update() mutated published version = %v, want %v", before, want)
+	}
+}
+
+func TestCOWSet_Replace(t *testing.T) {
+	s := NewCOWSet(1, 2)
+	s.Replace(3, 4)
+	if want := NewSet(3, 4); !s.Equal(want) {
+		t.Errorf("Replace() = %v, want %v", s, want)
+	}
+	s.Clear()
+	if !s.Empty() {
+		t.Errorf("Clear() = %v, want %v", s, "{}")
+	}
+}
+
+func TestCOWSet_Snapshot(t *testing.T) {
+	s := NewCOWSet(1, 2)
+	snap := s.Snapshot()
+	s.Removes(1)
+	if want := NewSet(1, 2); !snap.Equal(want) {
+		t.Errorf("Snapshot() = %v, want %v", snap, want)
+	}
+	if want := NewSet(2); !s.Equal(want) {
+		t.Errorf("Snapshot() source = %v, want %v", s, want)
+	}
+	if err := s.Restore(snap); err != nil || !s.Equal(snap) {
+		t.Errorf("Restore() = %v, %v, want %v", s, err, snap)
+	}
+	s.Adds(3)
+	if want := NewSet(1, 2); !snap.Equal(want) {
+		t.Errorf("Restore() shared snapshot = %v, want %v", snap, want)
+	}
+}
+
+func TestCOWSet_Algebra(t *testing.T) {
+	s := NewCOWSet(1, 2, 3, 4)
+	if got, want := s.Unions(NewSet(5)), NewSet(1, 2, 3, 4, 5); !got.Equal(want) {
+		t.Errorf("Unions() = %v, want %v", got, want)
+	}
+	if got, want := s.Intersections(NewSet(2, 3, 9)), NewSet(2, 3); !got.Equal(want) {
+		t.Errorf("Intersections() = %v, want %v", got, want)
+	}
+	if got, want := s.Complements(NewSet(1, 3)), NewSet(2, 4); !got.Equal(want) {
+		t.Errorf("Complements() = %v, want %v", got, want)
+	}
+	if !NewCOWSet(1).IsSub(s) {
+		t.Errorf("IsSub() = %v, want %v", false, true)
+	}
+}
+
+func TestCOWSet_Pop(t *testing.T) {
+	s := NewCOWSet()
+	for i := range elems {
+		s.Adds(elems[i])
+	}
+	wg := sync.WaitGroup{}
+	var mu sync.Mutex
+	popped := NewThreadUnsafeSet()
+	for range elems {
+		wg.Add(1)
+		go func() {
+			v := s.Pop()
+			mu.Lock()
+			popped.Adds(v)
+			mu.Unlock()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if !s.Empty() || popped.Cardinality() != len(elems) {
+		t.Errorf("Pop() = %v, want %v", popped.Cardinality(), len(elems))
+	}
+	if v := s.Pop(); v != nil {
+		t.Errorf("Pop() = %v, want %v", v, nil)
+	}
+}
+
+func BenchmarkCOWSet_Contains(b *testing.B) {
+	s := NewCOWSet()
+	for i := range elems {
+		s.Adds(elems[i])
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Contains(elems[i%len(elems)])
+		}
+	})
+}
+
+func BenchmarkSet_Contains(b *testing.B) {
+	s := NewSet()
+	for i := range elems {
+		s.Adds(elems[i])
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Contains(elems[i%len(elems)])
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package set
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// NewGSet returns a grow-only set CRDT holding elems.
+// Replicas of a GSet converge by merging their states in any order, any number of times.
+func NewGSet(elems ...interface{}) *GSet {
+	s := &GSet{added: make(threadUnsafeSet, len(elems))}
+	s.added.Adds(elems...)
+	return s
+}
+
+// GSet is a grow-only set (G-Set) CRDT: elements can be added but never removed, and Merge is a union.
+// It is not thread safe.
+type GSet struct {
+	added threadUnsafeSet
+}
+
+// Add adds elements to the replica. Returns whether all the items was added.
+func (s *GSet) Add(elems ...interface{}) bool {
+	return s.added.Adds(elems...)
+}
+
+// Contains returns whether the given items are all in the set.
+func (s *GSet) Contains(elems ...interface{}) bool {
+	return s.added.Contains(elems...)
+}
+
+// Cardinality returns the number of elements in the set.
+func (s *GSet) Cardinality() int {
+	return s.added.Cardinality()
+}
+
+// Value returns a copy of the current elements, see NewThreadUnsafeSet.
+func (s *GSet) Value() ISet {
+	return s.added.Clone()
+}
+
+// Merge folds the state of another replica into s.
+// Merge is commutative, associative and idempotent.
+func (s *GSet) Merge(other *GSet) {
+	for elem := range other.added {
+		s.added[elem] = struct{}{}
+	}
+}
+
+// Clone returns an independent copy of the replica.
+func (s *GSet) Clone() *GSet {
+	return &GSet{added: *s.added.Clone().(*threadUnsafeSet)}
+}
+
+// Equal returns whether two replicas have the same state.
+func (s *GSet) Equal(other *GSet) bool {
+	return s.added.Equal(&other.added)
+}
+
+// MarshalBinary encodes the replica state, see WriteSet for the supported element types.
+func (s *GSet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteSet(&buf, &s.added); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the replica state with one encoded by MarshalBinary.
+func (s *GSet) UnmarshalBinary(data []byte) error {
+	added := make(threadUnsafeSet)
+	if err := ReadSet(bytes.NewReader(data), &added); err != nil {
+		return err
+	}
+	s.added = added
+	return nil
+}
+
+func (s *GSet) String() string {
+	return s.added.String()
+}
+
+// NewTwoPhaseSet returns a two-phase set CRDT holding elems.
+func NewTwoPhaseSet(elems ...interface{}) *TwoPhaseSet {
+	s := &TwoPhaseSet{added: make(threadUnsafeSet, len(elems)), removed: make(threadUnsafeSet)}
+	s.added.Adds(elems...)
+	return s
+}
+
+// TwoPhaseSet is a two-phase set (2P-Set) CRDT: a pair of grow-only sets, one of added elements
+// and one of removed elements (tombstones). An element is in the set if it was added and never removed,
+// once removed it can never be added again. Merge unions both halves.
+// It is not thread safe.
+type TwoPhaseSet struct {
+	added   threadUnsafeSet
+	removed threadUnsafeSet
+}
+
+// Add adds elements to the replica. Returns whether all the items was added,
+// elements already present or removed before are not.
+func (s *TwoPhaseSet) Add(elems ...interface{}) bool {
+	var rejected bool
+	for i := 0; i < len(elems); i++ {
+		if _, ok := s.removed[elems[i]]; ok || !s.added.Adds(elems[i]) {
+			rejected = true
+		}
+	}
+	return !rejected
+}
+
+// Remove removes elements from the replica for good. Returns whether all the items was removed,
+// only elements currently in the set can be.
+func (s *TwoPhaseSet) Remove(elems ...interface{}) bool {
+	var rejected bool
+	for i := 0; i < len(elems); i++ {
+		if !s.Contains(elems[i]) {
+			rejected = true
+			continue
+		}
+		s.removed[elems[i]] = struct{}{}
+	}
+	return !rejected
+}
+
+// Contains returns whether the given items are all in the set.
+func (s *TwoPhaseSet) Contains(elems ...interface{}) bool {
+	for i := 0; i < len(elems); i++ {
+		if _, ok := s.removed[elems[i]]; ok || !s.added.Contains(elems[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cardinality returns the number of elements in the set.
+func (s *TwoPhaseSet) Cardinality() int {
+	return s.added.Cardinality() - s.removed.Cardinality()
+}
+
+// Value returns a copy of the current elements, see NewThreadUnsafeSet.
+func (s *TwoPhaseSet) Value() ISet {
+	return s.added.Complements(&s.removed)
+}
+
+// Merge folds the state of another replica into s.
+// Merge is commutative, associative and idempotent.
+func (s *TwoPhaseSet) Merge(other *TwoPhaseSet) {
+	for elem := range other.added {
+		s.added[elem] = struct{}{}
+	}
+	for elem := range other.removed {
+		s.removed[elem] = struct{}{}
+	}
+}
+
+// Clone returns an independent copy of the replica.
+func (s *TwoPhaseSet) Clone() *TwoPhaseSet {
+	return &TwoPhaseSet{
+		added:   *s.added.Clone().(*threadUnsafeSet),
+		removed: *s.removed.Clone().(*threadUnsafeSet),
+	}
+}
+
+// Equal returns whether two replicas have the same state, tombstones included.
+func (s *TwoPhaseSet) Equal(other *TwoPhaseSet) bool {
+	return s.added.Equal(&other.added) && s.removed.Equal(&other.removed)
+}
+
+// MarshalBinary encodes the replica state, see WriteSet for the supported element types.
+func (s *TwoPhaseSet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteSet(&buf, &s.added); err != nil {
+		return nil, err
+	}
+	if err := WriteSet(&buf, &s.removed); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the replica state with one encoded by MarshalBinary.
+func (s *TwoPhaseSet) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	added, removed := make(threadUnsafeSet), make(threadUnsafeSet)
+	if err := ReadSet(r, &added); err != nil {
+		return err
+	}
+	if err := ReadSet(r, &removed); err != nil {
+		return err
+	}
+	if !removed.IsSub(&added) {
+		return fmt.Errorf("go-set: TwoPhaseSet UnmarshalBinary() err, tombstones %v were never added", removed.Complements(&added))
+	}
+	s.added, s.removed = added, removed
+	return nil
+}
+
+func (s *TwoPhaseSet) String() string {
+	return s.Value().String()
+}
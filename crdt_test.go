@@ -0,0 +1,122 @@
+package set
+
+import (
+	"math/rand"
+	"testing"
+)
+
+const crdtPropertyRounds = 200
+
+// randomGSet returns a replica built from random operations over a small element domain, so replicas overlap.
+func randomGSet(r *rand.Rand) *GSet {
+	s := NewGSet()
+	for i := r.Intn(10); i > 0; i-- {
+		s.Add(r.Intn(20))
+	}
+	return s
+}
+
+func randomTwoPhaseSet(r *rand.Rand) *TwoPhaseSet {
+	s := NewTwoPhaseSet()
+	for i := r.Intn(15); i > 0; i-- {
+		if r.Intn(3) == 0 {
+			s.Remove(r.Intn(20))
+		} else {
+			s.Add(r.Intn(20))
+		}
+	}
+	return s
+}
+
+func mergedGSet(a, b *GSet) *GSet {
+	result := a.Clone()
+	result.Merge(b)
+	return result
+}
+
+func mergedTwoPhaseSet(a, b *TwoPhaseSet) *TwoPhaseSet {
+	result := a.Clone()
+	result.Merge(b)
+	return result
+}
+
+func TestGSet_MergeProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < crdtPropertyRounds; i++ {
+		a, b, c := randomGSet(r), randomGSet(r), randomGSet(r)
+		if !mergedGSet(a, b).Equal(mergedGSet(b, a)) {
+			t.Fatalf("Merge() not commutative for %v, %v", a, b)
+		}
+		if !mergedGSet(mergedGSet(a, b), c).Equal(mergedGSet(a, mergedGSet(b, c))) {
+			t.Fatalf("Merge() not associative for %v, %v, %v", a, b, c)
+		}
+		if !mergedGSet(a, a).Equal(a) || !mergedGSet(mergedGSet(a, b), b).Equal(mergedGSet(a, b)) {
+			t.Fatalf("Merge() not idempotent for %v, %v", a, b)
+		}
+	}
+}
+
+func TestTwoPhaseSet_MergeProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < crdtPropertyRounds; i++ {
+		a, b, c := randomTwoPhaseSet(r), randomTwoPhaseSet(r), randomTwoPhaseSet(r)
+		if !mergedTwoPhaseSet(a, b).Equal(mergedTwoPhaseSet(b, a)) {
+			t.Fatalf("Merge() not commutative for %v, %v", a, b)
+		}
+		if !mergedTwoPhaseSet(mergedTwoPhaseSet(a, b), c).Equal(mergedTwoPhaseSet(a, mergedTwoPhaseSet(b, c))) {
+			t.Fatalf("Merge() not associative for %v, %v, %v", a, b, c)
+		}
+		if !mergedTwoPhaseSet(a, a).Equal(a) || !mergedTwoPhaseSet(mergedTwoPhaseSet(a, b), b).Equal(mergedTwoPhaseSet(a, b)) {
+			t.Fatalf("Merge() not idempotent for %v, %v", a, b)
+		}
+	}
+}
+
+func TestGSet(t *testing.T) {
+	a, b := NewGSet(1, 2), NewGSet(2, 3)
+	if a.Add(1) || !a.Add(4) {
+		t.Errorf("Add() = %v, want %v", a, "{1,2,4}")
+	}
+	a.Merge(b)
+	if want := NewSet(1, 2, 3, 4); !a.Value().Equal(want) || a.Cardinality() != 4 || !a.Contains(3) {
+		t.Errorf("Merge() = %v, want %v", a, want)
+	}
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	got := NewGSet()
+	if err := got.UnmarshalBinary(data); err != nil || !got.Equal(a) {
+		t.Errorf("UnmarshalBinary() = %v, %v, want %v", got, err, a)
+	}
+}
+
+func TestTwoPhaseSet(t *testing.T) {
+	a := NewTwoPhaseSet(1, 2, 3)
+	if !a.Remove(1) || a.Remove(1) || a.Remove(9) {
+		t.Errorf("Remove() = %v, want %v", a, "{2,3}")
+	}
+	if a.Add(1) || a.Contains(1) {
+		t.Errorf("Add() of a removed element = %v, want %v", a, "{2,3}")
+	}
+	b := NewTwoPhaseSet(2, 3, 4)
+	b.Remove(3)
+	a.Merge(b)
+	if want := NewSet(2, 4); !a.Value().Equal(want) || a.Cardinality() != 2 {
+		t.Errorf("Merge() = %v, want %v", a, want)
+	}
+	if got := a.String(); got != "{2,4}" && got != "{4,2}" {
+		t.Errorf("String() = %v, want %v", got, "{2,4}")
+	}
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	got := NewTwoPhaseSet()
+	if err := got.UnmarshalBinary(data); err != nil || !got.Equal(a) {
+		t.Errorf("UnmarshalBinary() = %v, %v, want %v", got, err, a)
+	}
+	if err := got.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Errorf("UnmarshalBinary() error = %v, want an error", err)
+	}
+}
@@ -0,0 +1,518 @@
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned for mutations attempted on a DurableSet after Close.
+var ErrClosed = errors.New("go-set: DurableSet err, closed")
+
+// SyncPolicy controls when a DurableSet fsyncs its log.
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs the log before every mutation returns, a mutation that returned survives a crash.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval fsyncs the log in the background every DurableOptions.SyncInterval,
+	// a crash loses at most the mutations of the last interval.
+	SyncInterval
+	// SyncNever leaves flushing the log to the operating system.
+	SyncNever
+)
+
+// DurableOptions configures OpenDurableSet. The zero value fsyncs every mutation and never compacts automatically.
+type DurableOptions struct {
+	Sync SyncPolicy
+	// SyncInterval is the fsync period of SyncInterval, one second if zero.
+	SyncInterval time.Duration
+	// CompactEvery compacts the log into a snapshot after so many mutations, zero disables automatic compaction.
+	CompactEvery int
+}
+
+const (
+	durableSnapshotFile  = "snapshot"
+	durableLogFile       = "wal"
+	durableSnapshotMagic = "GOSETSN1"
+)
+
+// Log record operations.
+const (
+	walAdd byte = iota + 1
+	walRemove
+	walClear
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// OpenDurableSet opens, or creates, a durable set stored in dir and recovers its contents.
+// Mutations are appended to a checksummed write-ahead log before they are applied in memory,
+// and the log is periodically compacted into a snapshot of the whole set. On open, the snapshot
+// is loaded and the log replayed; a torn record at the end of the log, left by a crash, is discarded.
+// Elements must be nil, booleans, numbers or strings, see WriteSet.
+// dir must not be opened by two DurableSets at the same time.
+func OpenDurableSet(dir string, opts *DurableOptions) (*DurableSet, error) {
+	d := &DurableSet{dir: dir, m: NewThreadUnsafeSet().(*threadUnsafeSet)}
+	if opts != nil {
+		d.opts = *opts
+	}
+	if d.opts.SyncInterval <= 0 {
+		d.opts.SyncInterval = time.Second
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := d.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := d.replayLog(); err != nil {
+		return nil, err
+	}
+	if d.opts.Sync == SyncInterval {
+		d.stop, d.done = make(chan struct{}), make(chan struct{})
+		go d.syncLoop(d.stop)
+	}
+	return d, nil
+}
+
+// DurableSet is a thread safe, file backed ISet. See OpenDurableSet.
+//
+// ISet methods cannot report I/O errors, so a mutation whose log write fails is not applied
+// and returns false (nil for Pop); Err reports the reason. After an I/O error the log is
+// considered broken and every further mutation fails, reads keep working.
+// The set algebra methods and Clone return in-memory thread unsafe sets.
+type DurableSet struct {
+	rwm     sync.RWMutex
+	m       *threadUnsafeSet
+	dir     string
+	opts    DurableOptions
+	wal     *os.File
+	records int   // records appended since the last compaction
+	dirty   bool  // records appended since the last fsync
+	err     error // latest error that made a mutation fail
+	broken  error // I/O error that made the log unusable
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func (d *DurableSet) loadSnapshot() error {
+	data, err := ioutil.ReadFile(filepath.Join(d.dir, durableSnapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	header := len(durableSnapshotMagic) + 4
+	if len(data) < header || string(data[:len(durableSnapshotMagic)]) != durableSnapshotMagic {
+		return fmt.Errorf("go-set: OpenDurableSet() err, %s is not a snapshot", durableSnapshotFile)
+	}
+	payload := data[header:]
+	if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(data[len(durableSnapshotMagic):]) {
+		return fmt.Errorf("go-set: OpenDurableSet() err, %s checksum mismatch", durableSnapshotFile)
+	}
+	return ReadSet(bytes.NewReader(payload), d.m)
+}
+
+// replayLog applies the log to the in-memory set, truncates a torn tail and opens the log for appending.
+// A tail is torn when it is too short for its record or fails its checksum.
+// Replaying is idempotent, so a crash between writing a snapshot and truncating the log is harmless.
+func (d *DurableSet) replayLog() error {
+	f, err := os.OpenFile(filepath.Join(d.dir, durableLogFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	var off int
+	for off+8 <= len(data) {
+		l := int(binary.LittleEndian.Uint32(data[off:]))
+		sum := binary.LittleEndian.Uint32(data[off+4:])
+		if l > len(data)-off-8 {
+			break
+		}
+		payload := data[off+8 : off+8+l]
+		if crc32.Checksum(payload, castagnoli) != sum {
+			break
+		}
+		// The record was written in full, so failing to apply it is corruption, not a torn tail:
+		// truncating would lose it and every acknowledged write after it.
+		if err := d.applyRecord(payload); err != nil {
+			f.Close()
+			return fmt.Errorf("go-set: OpenDurableSet() err, %s record at offset %d: %v", durableLogFile, off, err)
+		}
+		off += 8 + l
+		d.records++
+	}
+	if off < len(data) {
+		if err := f.Truncate(int64(off)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := f.Seek(int64(off), io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	d.wal = f
+	return nil
+}
+
+func (d *DurableSet) applyRecord(payload []byte) error {
+	if len(payload) == 0 {
+		return errShortElem
+	}
+	op := payload[0]
+	r := bytes.NewReader(payload[1:])
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if n > uint64(r.Len()) {
+		return errShortElem
+	}
+	elems := make([]interface{}, 0, n)
+	var buf []byte
+	for i := uint64(0); i < n; i++ {
+		var elem interface{}
+		if elem, buf, err = readElem(r, buf); err != nil {
+			return err
+		}
+		elems = append(elems, elem)
+	}
+	switch op {
+	case walAdd:
+		d.m.Adds(elems...)
+	case walRemove:
+		d.m.Removes(elems...)
+	case walClear:
+		d.m.Clear()
+	default:
+		return fmt.Errorf("go-set: DurableSet err, unknown log operation %d", op)
+	}
+	return nil
+}
+
+// appendRecord writes a log record and fsyncs it according to the sync policy. The caller must hold the write lock.
+func (d *DurableSet) appendRecord(op byte, elems []interface{}) error {
+	if d.broken != nil {
+		d.err = d.broken
+		return d.broken
+	}
+	payload := appendUvarint([]byte{op}, uint64(len(elems)))
+	w := bytes.NewBuffer(payload)
+	for _, elem := range elems {
+		if err := writeElem(w, elem, nil); err != nil {
+			d.err = err
+			return err
+		}
+	}
+	payload = w.Bytes()
+	record := make([]byte, 8, 8+len(payload))
+	binary.LittleEndian.PutUint32(record, uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload, castagnoli))
+	record = append(record, payload...)
+	if _, err := d.wal.Write(record); err != nil {
+		d.err, d.broken = err, err
+		return err
+	}
+	d.dirty = true
+	if d.opts.Sync == SyncAlways {
+		if err := d.syncLocked(); err != nil {
+			return err
+		}
+	}
+	d.records++
+	return nil
+}
+
+// afterAppend compacts the log when it has grown past DurableOptions.CompactEvery. The caller must hold the write lock.
+func (d *DurableSet) afterAppend() {
+	if d.opts.CompactEvery > 0 && d.records >= d.opts.CompactEvery {
+		d.compactLocked()
+	}
+}
+
+func (d *DurableSet) syncLocked() error {
+	if !d.dirty || d.broken != nil {
+		return d.broken
+	}
+	if err := d.wal.Sync(); err != nil {
+		d.err, d.broken = err, err
+		return err
+	}
+	d.dirty = false
+	return nil
+}
+
+func (d *DurableSet) syncLoop(stop <-chan struct{}) {
+	defer close(d.done)
+	ticker := time.NewTicker(d.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			d.Sync()
+		}
+	}
+}
+
+// Err returns the error that made the latest failed mutation fail, nil if none failed.
+func (d *DurableSet) Err() error {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.err
+}
+
+// Sync fsyncs the log, making every mutation so far durable whatever the sync policy.
+func (d *DurableSet) Sync() error {
+	d.rwm.Lock()
+	defer d.rwm.Unlock()
+	return d.syncLocked()
+}
+
+// Compact writes the whole set to a new snapshot and empties the log.
+func (d *DurableSet) Compact() error {
+	d.rwm.Lock()
+	defer d.rwm.Unlock()
+	return d.compactLocked()
+}
+
+func (d *DurableSet) compactLocked() error {
+	if d.broken != nil {
+		return d.broken
+	}
+	var payload bytes.Buffer
+	if err := WriteSet(&payload, d.m); err != nil {
+		d.err = err
+		return err
+	}
+	header := make([]byte, len(durableSnapshotMagic)+4)
+	copy(header, durableSnapshotMagic)
+	binary.LittleEndian.PutUint32(header[len(durableSnapshotMagic):], crc32.Checksum(payload.Bytes(), castagnoli))
+
+	tmp := filepath.Join(d.dir, durableSnapshotFile+".tmp")
+	if err := writeFileSync(tmp, header, payload.Bytes()); err != nil {
+		d.err = err
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(d.dir, durableSnapshotFile)); err != nil {
+		d.err = err
+		return err
+	}
+	syncDir(d.dir)
+	// The snapshot is durable, the log can go. Until it is truncated, replaying it on top of the snapshot is harmless.
+	if err := d.wal.Truncate(0); err != nil {
+		d.err, d.broken = err, err
+		return err
+	}
+	if _, err := d.wal.Seek(0, io.SeekStart); err != nil {
+		d.err, d.broken = err, err
+		return err
+	}
+	d.records, d.dirty = 0, true
+	return d.syncLocked()
+}
+
+func writeFileSync(name string, chunks ...[]byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if _, err := f.Write(chunk); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir makes a rename in dir durable. Some platforms cannot fsync directories, errors are ignored.
+func syncDir(dir string) {
+	if f, err := os.Open(dir); err == nil {
+		f.Sync()
+		f.Close()
+	}
+}
+
+// Close syncs and closes the log. Mutations fail with ErrClosed afterwards, reads keep working.
+func (d *DurableSet) Close() error {
+	// The sync loop takes the lock, so wait for it after releasing the lock.
+	d.rwm.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.rwm.Unlock()
+	if stop != nil {
+		close(stop)
+		<-d.done
+	}
+	d.rwm.Lock()
+	defer d.rwm.Unlock()
+	if d.broken == ErrClosed {
+		return nil
+	}
+	err := d.syncLocked()
+	if cerr := d.wal.Close(); err == nil {
+		err = cerr
+	}
+	d.broken = ErrClosed
+	return err
+}
+
+func (d *DurableSet) Empty() bool {
+	return d.Cardinality() == 0
+}
+
+func (d *DurableSet) Singleton() bool {
+	return d.Cardinality() == 1
+}
+
+func (d *DurableSet) Cardinality() int {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.Cardinality()
+}
+
+func (d *DurableSet) ToSlice() ISlice {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.ToSlice()
+}
+
+func (d *DurableSet) Adds(elems ...interface{}) bool {
+	d.rwm.Lock()
+	defer d.rwm.Unlock()
+	var added []interface{}
+	for i := 0; i < len(elems); i++ {
+		if !d.m.Contains(elems[i]) {
+			added = append(added, elems[i])
+		}
+	}
+	if len(added) > 0 {
+		if d.appendRecord(walAdd, added) != nil {
+			return false
+		}
+		defer d.afterAppend()
+	}
+	return d.m.Adds(elems...)
+}
+
+func (d *DurableSet) Removes(elems ...interface{}) bool {
+	d.rwm.Lock()
+	defer d.rwm.Unlock()
+	var removed []interface{}
+	for i := 0; i < len(elems); i++ {
+		if d.m.Contains(elems[i]) {
+			removed = append(removed, elems[i])
+		}
+	}
+	if len(removed) > 0 {
+		if d.appendRecord(walRemove, removed) != nil {
+			return false
+		}
+		defer d.afterAppend()
+	}
+	return d.m.Removes(elems...)
+}
+
+func (d *DurableSet) IsSub(other ISet) bool {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.IsSub(other)
+}
+
+func (d *DurableSet) Unions(others ...ISet) ISet {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.Unions(others...)
+}
+
+func (d *DurableSet) Intersections(others ...ISet) ISet {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.Intersections(others...)
+}
+
+func (d *DurableSet) Complements(others ...ISet) ISet {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.Complements(others...)
+}
+
+func (d *DurableSet) Clear() {
+	d.rwm.Lock()
+	defer d.rwm.Unlock()
+	if d.m.Empty() || d.appendRecord(walClear, nil) != nil {
+		return
+	}
+	d.m.Clear()
+	d.afterAppend()
+}
+
+func (d *DurableSet) Contains(elems ...interface{}) bool {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.Contains(elems...)
+}
+
+func (d *DurableSet) Clone() ISet {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.Clone()
+}
+
+func (d *DurableSet) Equal(other ISet) bool {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.Equal(other)
+}
+
+func (d *DurableSet) Pop() interface{} {
+	d.rwm.Lock()
+	defer d.rwm.Unlock()
+	if d.m.Empty() {
+		return nil
+	}
+	elem := d.m.Pop()
+	if d.appendRecord(walRemove, []interface{}{elem}) != nil {
+		d.m.Adds(elem)
+		return nil
+	}
+	d.afterAppend()
+	return elem
+}
+
+func (d *DurableSet) String() string {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.String()
+}
+
+func (d *DurableSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", d)
+}
+
+func (d *DurableSet) Hash() uint64 {
+	d.rwm.RLock()
+	defer d.rwm.RUnlock()
+	return d.m.Hash()
+}
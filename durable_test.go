@@ -0,0 +1,194 @@
+package set
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openTestDurableSet(t *testing.T, dir string, opts *DurableOptions) *DurableSet {
+	t.Helper()
+	d, err := OpenDurableSet(dir, opts)
+	if err != nil {
+		t.Fatalf("OpenDurableSet() error = %v", err)
+	}
+	return d
+}
+
+func TestDurableSet_Recover(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurableSet(t, dir, nil)
+	d.Adds(1, "a", 2.5)
+	d.Removes("a")
+	d.Adds(int64(3))
+	if elem := d.Pop(); elem == nil {
+		t.Errorf("Pop() = %v, want an element", elem)
+	}
+	want := d.Clone()
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	d = openTestDurableSet(t, dir, nil)
+	defer d.Close()
+	if !d.Equal(want) {
+		t.Errorf("OpenDurableSet() = %v, want %v", d, want)
+	}
+	d.Clear()
+	d.Adds(true)
+	d.Close()
+
+	d = openTestDurableSet(t, dir, nil)
+	defer d.Close()
+	if want := NewSet(true); !d.Equal(want) {
+		t.Errorf("OpenDurableSet() = %v, want %v", d, want)
+	}
+}
+
+func TestDurableSet_TornLog(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurableSet(t, dir, nil)
+	d.Adds(1)
+	d.Adds(2)
+	d.Close()
+
+	wal := filepath.Join(dir, durableLogFile)
+	info, _ := os.Stat(wal)
+	f, _ := os.OpenFile(wal, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte{9, 0, 0, 0, 1, 2, 3})
+	f.Close()
+
+	d = openTestDurableSet(t, dir, nil)
+	if want := NewSet(1, 2); !d.Equal(want) {
+		t.Errorf("OpenDurableSet() = %v, want %v", d, want)
+	}
+	if after, _ := os.Stat(wal); after.Size() != info.Size() {
+		t.Errorf("OpenDurableSet() log size = %v, want %v", after.Size(), info.Size())
+	}
+	d.Adds(3)
+	d.Close()
+
+	// Flip a byte of the second record: recovery keeps the first one only.
+	data, _ := ioutil.ReadFile(wal)
+	data[len(data)/2]++
+	ioutil.WriteFile(wal, data, 0o644)
+	d = openTestDurableSet(t, dir, nil)
+	defer d.Close()
+	if want := NewSet(1); !d.Equal(want) {
+		t.Errorf("OpenDurableSet() = %v, want %v", d, want)
+	}
+}
+
+func TestDurableSet_UndecodableRecord(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurableSet(t, dir, nil)
+	d.Adds(1)
+	d.Close()
+
+	// A record with a valid checksum but an unknown operation is not torn: Open fails and keeps the log.
+	payload := []byte{99, 0}
+	record := make([]byte, 8, 8+len(payload))
+	binary.LittleEndian.PutUint32(record, uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload, castagnoli))
+	wal := filepath.Join(dir, durableLogFile)
+	f, _ := os.OpenFile(wal, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write(append(record, payload...))
+	f.Close()
+	before, _ := os.Stat(wal)
+
+	if d, err := OpenDurableSet(dir, nil); err == nil {
+		d.Close()
+		t.Errorf("OpenDurableSet() error = %v, want an error", err)
+	}
+	if after, _ := os.Stat(wal); after.Size() != before.Size() {
+		t.Errorf("OpenDurableSet() log size = %v, want %v", after.Size(), before.Size())
+	}
+}
+
+func TestDurableSet_Compact(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurableSet(t, dir, &DurableOptions{Sync: SyncNever, CompactEvery: 3})
+	for i := 0; i < 10; i++ {
+		d.Adds(i)
+	}
+	d.Removes(0)
+	d.Close()
+	if _, err := os.Stat(filepath.Join(dir, durableSnapshotFile)); err != nil {
+		t.Errorf("Compact() snapshot error = %v", err)
+	}
+	d = openTestDurableSet(t, dir, nil)
+	if want := NewSet(1, 2, 3, 4, 5, 6, 7, 8, 9); !d.Equal(want) {
+		t.Errorf("OpenDurableSet() = %v, want %v", d, want)
+	}
+	if err := d.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, durableLogFile)); info.Size() != 0 {
+		t.Errorf("Compact() log size = %v, want %v", info.Size(), 0)
+	}
+	d.Close()
+
+	// A crash after the snapshot was written but before the log was truncated replays the log on top of it.
+	d = openTestDurableSet(t, dir, nil)
+	d.Adds(100)
+	d.Removes(1)
+	wal, _ := ioutil.ReadFile(filepath.Join(dir, durableLogFile))
+	d.Compact()
+	d.Close()
+	ioutil.WriteFile(filepath.Join(dir, durableLogFile), wal, 0o644)
+	d = openTestDurableSet(t, dir, nil)
+	defer d.Close()
+	if want := NewSet(2, 3, 4, 5, 6, 7, 8, 9, 100); !d.Equal(want) {
+		t.Errorf("OpenDurableSet() = %v, want %v", d, want)
+	}
+}
+
+func TestDurableSet_Errors(t *testing.T) {
+	d := openTestDurableSet(t, t.TempDir(), &DurableOptions{Sync: SyncInterval, SyncInterval: time.Millisecond})
+	if d.Adds(1, struct{}{}) || d.Contains(1) {
+		t.Errorf("Adds() unencodable = %v, want %v", true, false)
+	}
+	if d.Err() == nil {
+		t.Errorf("Err() = %v, want an error", nil)
+	}
+	if !d.Adds(1) {
+		t.Errorf("Adds() after unencodable = %v, want %v", false, true)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if d.Adds(2) || d.Err() != ErrClosed {
+		t.Errorf("Adds() after Close() err = %v, want %v", d.Err(), ErrClosed)
+	}
+	if !d.Contains(1) {
+		t.Errorf("Contains() after Close() = %v, want %v", false, true)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("Close() twice error = %v", err)
+	}
+}
+
+func TestDurableSet_ConcurrentClose(t *testing.T) {
+	d := openTestDurableSet(t, t.TempDir(), &DurableOptions{Sync: SyncInterval, SyncInterval: time.Millisecond})
+	d.Adds(1)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if d.Adds(2) || d.Err() != ErrClosed {
+		t.Errorf("Adds() after Close() err = %v, want %v", d.Err(), ErrClosed)
+	}
+}
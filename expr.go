@@ -0,0 +1,387 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrUnknownSet is wrapped by the errors reporting a name missing from the sets an expression is evaluated on.
+var ErrUnknownSet = errors.New("go-set: unknown set")
+
+type exprOp int
+
+const (
+	opName exprOp = iota
+	opLiteral
+	opUnion
+	opSymDiff
+	opIntersect
+	opDiff
+)
+
+// exprOps maps the operators to their operation, the ASCII ones are those of Python sets.
+var exprOps = map[rune]exprOp{
+	'∪': opUnion, '|': opUnion,
+	'△': opSymDiff, '∆': opSymDiff, '^': opSymDiff,
+	'∩': opIntersect, '&': opIntersect,
+	'\\': opDiff, '∖': opDiff, '-': opDiff,
+}
+
+var exprSymbols = map[exprOp]string{opUnion: "∪", opSymDiff: "△", opIntersect: "∩", opDiff: "\\"}
+
+type exprNode struct {
+	op    exprOp
+	pos   int // offset in the source
+	name  string
+	elems []interface{}
+	args  []*exprNode
+}
+
+// Expr is a compiled set expression, see ParseExpr. It is safe for concurrent use.
+type Expr struct {
+	root *exprNode
+}
+
+// ParseExpr compiles an expression over named sets, such as (admins ∪ owners) \ suspended ∩ region_eu.
+//
+// The operators are, from the loosest to the tightest binding, as for Python sets:
+// union ∪ or |, symmetric difference △ or ^, intersection ∩ or &, and difference \ or -.
+// They are left associative, and parentheses group. Names are made of letters, digits, '_', '.' and ':',
+// other names are double-quoted with Go escapes. Literal sets in the notation of Parse are allowed too,
+// as in staff - {root}. Malformed expressions return a *SyntaxError.
+// Examples:
+// e, _ := ParseExpr("(admins | owners) - suspended & region_eu")
+// e.Eval(map[string]ISet{"admins": ..., "owners": ..., "suspended": ..., "region_eu": ...})
+func ParseExpr(s string) (*Expr, error) {
+	p := &exprParser{parser: parser{fn: "ParseExpr", s: s}}
+	root, err := p.binary(opUnion, 0)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return &Expr{root: root}, nil
+}
+
+// Eval compiles expr with ParseExpr and evaluates it on sets.
+func Eval(expr string, sets map[string]ISet) (ISet, error) {
+	e, err := ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return e.Eval(sets)
+}
+
+type exprParser struct {
+	parser
+}
+
+func (p *exprParser) peek() rune {
+	r, _ := utf8.DecodeRuneInString(p.s[p.pos:])
+	return r
+}
+
+// binary parses a chain of operations of precedence op, or tighter, into one n-ary node.
+func (p *exprParser) binary(op exprOp, depth int) (*exprNode, error) {
+	operand := func() (*exprNode, error) {
+		if op == opDiff {
+			return p.primary(depth)
+		}
+		return p.binary(op+1, depth)
+	}
+	node, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for chained := false; ; chained = true {
+		p.skipSpace()
+		if next, ok := exprOps[p.peek()]; p.pos == len(p.s) || !ok || next != op {
+			return node, nil
+		}
+		pos := p.pos
+		p.pos += utf8.RuneLen(p.peek())
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if !chained && node.op != op {
+			node = &exprNode{op: op, pos: pos, args: []*exprNode{node}}
+		} else if !chained {
+			node = &exprNode{op: op, pos: node.pos, args: append([]*exprNode(nil), node.args...)} // as in (a ∪ b) ∪ c
+		}
+		if right.op == op && op != opDiff {
+			node.args = append(node.args, right.args...) // associative, as in a ∪ (b ∪ c)
+		} else {
+			node.args = append(node.args, right)
+		}
+	}
+}
+
+func isExprOp(r rune) bool {
+	_, ok := exprOps[r]
+	return ok
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == ':'
+}
+
+func (p *exprParser) primary(depth int) (*exprNode, error) {
+	if depth == maxParseDepth {
+		return nil, p.errorf("expression nested deeper than %d", maxParseDepth)
+	}
+	if p.skipSpace(); p.pos == len(p.s) {
+		return nil, p.errorf("missing set")
+	}
+	start := p.pos
+	switch r := p.peek(); {
+	case r == '(':
+		p.pos++
+		node, err := p.binary(opUnion, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace(); p.pos == len(p.s) || p.s[p.pos] != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+		p.pos++
+		return node, nil
+	case r == '{':
+		elems, err := p.set(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &exprNode{op: opLiteral, pos: start, elems: elems}, nil
+	case r == '"':
+		name, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNode{op: opName, pos: start, name: name.(string)}, nil
+	case isNameRune(r):
+		for p.pos < len(p.s) && isNameRune(p.peek()) {
+			p.pos += utf8.RuneLen(p.peek())
+		}
+		return &exprNode{op: opName, pos: start, name: p.s[start:p.pos]}, nil
+	default:
+		return nil, p.errorf("unexpected %q", r)
+	}
+}
+
+// Names returns the sorted names of the sets the expression refers to.
+func (e *Expr) Names() []string {
+	seen := make(map[string]struct{})
+	var walk func(n *exprNode)
+	walk = func(n *exprNode) {
+		if n.op == opName {
+			seen[n.name] = struct{}{}
+		}
+		for _, arg := range n.args {
+			walk(arg)
+		}
+	}
+	walk(e.root)
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns the expression with symbolic operators, and parentheses around every nested operation.
+func (e *Expr) String() string {
+	var b strings.Builder
+	var walk func(n *exprNode, nested bool)
+	walk = func(n *exprNode, nested bool) {
+		switch n.op {
+		case opName:
+			if name := n.name; name == "" || strings.IndexFunc(name, func(r rune) bool { return !isNameRune(r) }) >= 0 {
+				b.WriteString(strconv.Quote(name))
+			} else {
+				b.WriteString(name)
+			}
+			return
+		case opLiteral:
+			b.WriteString(formatElems(append([]interface{}(nil), n.elems...), -1, false))
+			return
+		}
+		if nested {
+			b.WriteByte('(')
+		}
+		for i, arg := range n.args {
+			if i > 0 {
+				b.WriteString(" " + exprSymbols[n.op] + " ")
+			}
+			walk(arg, true)
+		}
+		if nested {
+			b.WriteByte(')')
+		}
+	}
+	walk(e.root, false)
+	return b.String()
+}
+
+// Eval evaluates the expression on the named sets, and returns the result as a new set, see NewSet.
+// The sets are only read, and a name missing from sets returns an error wrapping ErrUnknownSet,
+// whether or not the result depends on it.
+//
+// Intersections are planned: operands that are names or literals come first and an empty one
+// short-circuits the evaluation of the others, then the elements of the smallest operand are probed
+// in the others from the smallest to the largest.
+// Differences stop evaluating their subtrahends as soon as the result is empty.
+func (e *Expr) Eval(sets map[string]ISet) (ISet, error) {
+	return e.EvalFunc(func(name string) (ISet, bool) {
+		s, ok := sets[name]
+		return s, ok
+	})
+}
+
+// EvalFunc is like Eval, but resolves names with lookup, once each.
+func (e *Expr) EvalFunc(lookup func(name string) (ISet, bool)) (ISet, error) {
+	sets, err := e.resolve(lookup)
+	if err != nil {
+		return nil, err
+	}
+	s, owned, err := evalExpr(e.root, func(name string) (ISet, bool) {
+		s, ok := sets[name]
+		return s, ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		s = NewSet(s.ToSlice().Interface()...)
+	}
+	return s, nil
+}
+
+// resolve looks up every name before evaluating, so that an unknown one is reported
+// even where an empty operand spares evaluating it. Names are resolved in the order they appear.
+func (e *Expr) resolve(lookup func(string) (ISet, bool)) (map[string]ISet, error) {
+	sets := make(map[string]ISet)
+	var walk func(n *exprNode) error
+	walk = func(n *exprNode) error {
+		if n.op == opName {
+			if _, ok := sets[n.name]; ok {
+				return nil
+			}
+			s, ok := lookup(n.name)
+			if !ok {
+				return fmt.Errorf("go-set: Expr Eval() err, set %q at offset %d: %w", n.name, n.pos, ErrUnknownSet)
+			}
+			sets[n.name] = s
+		}
+		for _, arg := range n.args {
+			if err := walk(arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return sets, walk(e.root)
+}
+
+// evalExpr evaluates n, owned reports whether the result is a new set that may be modified.
+func evalExpr(n *exprNode, lookup func(string) (ISet, bool)) (s ISet, owned bool, err error) {
+	switch n.op {
+	case opName:
+		s, _ := lookup(n.name) // resolved up front, see resolve
+		return s, false, nil
+	case opLiteral:
+		return NewSet(n.elems...), true, nil
+	case opIntersect:
+		return evalIntersect(n.args, lookup)
+	}
+	first, owned, err := evalExpr(n.args[0], lookup)
+	if err != nil {
+		return nil, false, err
+	}
+	if n.op == opUnion {
+		return evalUnion(first, owned, n.args[1:], lookup)
+	}
+	result := first
+	if !owned {
+		result = NewSet(first.ToSlice().Interface()...)
+	}
+	for _, arg := range n.args[1:] {
+		if n.op == opDiff && result.Empty() {
+			break
+		}
+		other, _, err := evalExpr(arg, lookup)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, elem := range other.ToSlice().Interface() {
+			if !result.Removes(elem) && n.op == opSymDiff {
+				result.Adds(elem)
+			}
+		}
+	}
+	return result, true, nil
+}
+
+// evalUnion adds the other operands to the largest one, which it copies unless it is owned.
+func evalUnion(first ISet, owned bool, args []*exprNode, lookup func(string) (ISet, bool)) (ISet, bool, error) {
+	operands := []ISet{first}
+	base := 0
+	for _, arg := range args {
+		s, ok, err := evalExpr(arg, lookup)
+		if err != nil {
+			return nil, false, err
+		}
+		if s.Cardinality() > operands[base].Cardinality() {
+			base, owned = len(operands), ok
+		}
+		operands = append(operands, s)
+	}
+	result := operands[base]
+	if !owned {
+		result = NewSet(result.ToSlice().Interface()...)
+	}
+	for i, s := range operands {
+		if i != base {
+			result.Adds(s.ToSlice().Interface()...)
+		}
+	}
+	return result, true, nil
+}
+
+func evalIntersect(args []*exprNode, lookup func(string) (ISet, bool)) (ISet, bool, error) {
+	operands := make([]ISet, 0, len(args))
+	// Leaves are cheap to resolve, evaluate them first so an empty one spares evaluating the rest.
+	for pass := 0; pass < 2; pass++ {
+		for _, arg := range args {
+			if leaf := arg.op == opName || arg.op == opLiteral; leaf != (pass == 0) {
+				continue
+			}
+			s, _, err := evalExpr(arg, lookup)
+			if err != nil {
+				return nil, false, err
+			}
+			if s.Empty() {
+				return NewSet(), true, nil
+			}
+			operands = append(operands, s)
+		}
+	}
+	sort.SliceStable(operands, func(i, j int) bool { return operands[i].Cardinality() < operands[j].Cardinality() })
+	result := NewSet()
+Loop:
+	for _, elem := range operands[0].ToSlice().Interface() {
+		for _, other := range operands[1:] {
+			if !other.Contains(elem) {
+				continue Loop
+			}
+		}
+		result.Adds(elem)
+	}
+	return result, true, nil
+}
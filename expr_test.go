@@ -0,0 +1,165 @@
+package set
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func exprSets() map[string]ISet {
+	return map[string]ISet{
+		"admins":    NewSet("ann", "bob"),
+		"owners":    NewSet("bob", "cid", "dan"),
+		"suspended": NewSet("cid", "eve"),
+		"region_eu": NewSet("ann", "bob", "cid", "eve"),
+		"empty":     NewSet(),
+		"a-b":       NewSet("x"),
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want ISet
+	}{
+		{"admins", NewSet("ann", "bob")},
+		{"admins ∪ owners", NewSet("ann", "bob", "cid", "dan")},
+		{"admins | owners", NewSet("ann", "bob", "cid", "dan")},
+		{"admins ∩ owners", NewSet("bob")},
+		{"owners \\ suspended", NewSet("bob", "dan")},
+		{"owners - suspended", NewSet("bob", "dan")},
+		{"admins △ owners", NewSet("ann", "cid", "dan")},
+		{"admins ^ owners ^ suspended", NewSet("ann", "dan", "eve")},
+		{"(admins ∪ owners) \\ suspended ∩ region_eu", NewSet("ann", "bob")},
+		{"admins | owners - suspended", NewSet("ann", "bob", "dan")},
+		{"admins | owners & suspended", NewSet("ann", "bob", "cid")},
+		{"owners - suspended - admins", NewSet("dan")},
+		{"owners - (suspended - region_eu)", NewSet("bob", "cid", "dan")},
+		{"region_eu & owners & admins & region_eu", NewSet("bob")},
+		{"owners & empty & (admins | owners)", NewSet()},
+		{`"a-b" | {y,"a b"}`, NewSet("x", "y", "a b")},
+		{"admins - {bob}", NewSet("ann")},
+		{"{1,2} & {2,3}", NewSet(2)},
+	}
+	for _, tt := range tests {
+		got, err := Eval(tt.expr, exprSets())
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("Eval(%q) = %v, %v, want %v", tt.expr, got, err, tt.want)
+		}
+	}
+}
+
+func TestEval_Copies(t *testing.T) {
+	sets := exprSets()
+	for _, expr := range []string{"admins", "admins | empty", "admins - empty", "admins & admins"} {
+		got, _ := Eval(expr, sets)
+		got.Adds("zed")
+		if sets["admins"].Contains("zed") {
+			t.Fatalf("Eval(%q) returned the named set %v", expr, sets["admins"])
+		}
+	}
+}
+
+func TestEval_Error(t *testing.T) {
+	if _, err := Eval("admins | nobody", exprSets()); !errors.Is(err, ErrUnknownSet) {
+		t.Errorf("Eval() error = %v, want %v", err, ErrUnknownSet)
+	}
+	// Unknown names are reported even where an empty operand makes them irrelevant.
+	sets := map[string]ISet{"e": NewSet()}
+	for _, expr := range []string{"e & missing", "missing & e", "e - missing", "e & (e | missing)"} {
+		if _, err := Eval(expr, sets); !errors.Is(err, ErrUnknownSet) {
+			t.Errorf("Eval(%q) error = %v, want %v", expr, err, ErrUnknownSet)
+		}
+	}
+	tests := []struct {
+		expr   string
+		offset int
+	}{
+		{"", 0},
+		{"admins |", 8},
+		{"(admins", 7},
+		{"admins owners", 7},
+		{"admins + owners", 7},
+		{"admins | {1,", 12},
+		{`"admins`, 0},
+		{")", 0},
+	}
+	for _, tt := range tests {
+		_, err := ParseExpr(tt.expr)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || syntaxErr.Offset != tt.offset || syntaxErr.Func != "ParseExpr" {
+			t.Errorf("ParseExpr(%q) error = %v, want a *SyntaxError at offset %d", tt.expr, err, tt.offset)
+		}
+	}
+}
+
+// readCountingSet counts the reads of the elements of a set.
+type readCountingSet struct {
+	ISet
+	reads *int
+}
+
+func (s readCountingSet) ToSlice() ISlice {
+	*s.reads++
+	return s.ISet.ToSlice()
+}
+
+func (s readCountingSet) Contains(elems ...interface{}) bool {
+	*s.reads++
+	return s.ISet.Contains(elems...)
+}
+
+func TestExpr_Plan(t *testing.T) {
+	var looked []string
+	reads := make(map[string]*int)
+	lookup := func(name string) (ISet, bool) {
+		looked = append(looked, name)
+		s, ok := exprSets()[name]
+		reads[name] = new(int)
+		return readCountingSet{ISet: s, reads: reads[name]}, ok
+	}
+	e, _ := ParseExpr("(admins | owners) & empty & (suspended - region_eu)")
+	got, err := e.EvalFunc(lookup)
+	if err != nil || !got.Empty() {
+		t.Errorf("EvalFunc() = %v, %v, want %v", got, err, "{}")
+	}
+	// Every name is resolved once, in order, but the empty operand spares reading the others.
+	if want := []string{"admins", "owners", "empty", "suspended", "region_eu"}; !reflect.DeepEqual(looked, want) {
+		t.Errorf("EvalFunc() looked up %v, want %v", looked, want)
+	}
+	for name, n := range reads {
+		if *n != 0 {
+			t.Errorf("EvalFunc() read %v %d times, want 0", name, *n)
+		}
+	}
+	looked = nil
+	e, _ = ParseExpr("empty - (admins | owners)")
+	if got, err := e.EvalFunc(lookup); err != nil || !got.Empty() || len(looked) != 3 {
+		t.Errorf("EvalFunc() = %v, %v, looked up %v, want %v", got, err, looked, "{}")
+	}
+}
+
+func TestExpr_String(t *testing.T) {
+	tests := []struct {
+		expr, want string
+		names      []string
+	}{
+		{"a|b|c", "a ∪ b ∪ c", []string{"a", "b", "c"}},
+		{"(a|b)|(c|a)", "a ∪ b ∪ c ∪ a", []string{"a", "b", "c"}},
+		{"a | b - c & d ^ e", "a ∪ (((b \\ c) ∩ d) △ e)", []string{"a", "b", "c", "d", "e"}},
+		{"a - (b - c)", "a \\ (b \\ c)", []string{"a", "b", "c"}},
+		{`"x y" & {2,1}`, `"x y" ∩ {1,2}`, []string{"x y"}},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q) error = %v", tt.expr, err)
+		}
+		if got := e.String(); got != tt.want {
+			t.Errorf("ParseExpr(%q).String() = %v, want %v", tt.expr, got, tt.want)
+		}
+		if got := e.Names(); !reflect.DeepEqual(got, tt.names) {
+			t.Errorf("ParseExpr(%q).Names() = %v, want %v", tt.expr, got, tt.names)
+		}
+	}
+}
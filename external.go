@@ -0,0 +1,601 @@
+package set
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ExternalFormat is the format of a set stored in a file, see ExternalSource.
+type ExternalFormat int
+
+const (
+	// FormatLines is text with one string element per line. Lines end with "\n" or "\r\n".
+	FormatLines ExternalFormat = iota
+	// FormatBinary is the format of WriteSet and ReadSet.
+	FormatBinary
+	// FormatSorted is the format of BuildSortedFile and OpenSortedFile. Sorted inputs are streamed without sorting.
+	FormatSorted
+)
+
+// ExternalSource is a set stored in a file, its elements may be repeated and in any order (except FormatSorted).
+type ExternalSource struct {
+	Path   string
+	Format ExternalFormat
+}
+
+// ExternalOptions configures the external set operations, the zero value is usable.
+type ExternalOptions struct {
+	// TempDir holds the sorted runs, os.TempDir() if empty.
+	TempDir string
+	// MaxMemory bounds, in bytes of encoded elements, how much of an input is sorted in memory at once.
+	// 64MiB if zero.
+	MaxMemory int
+}
+
+const defaultExternalMaxMemory = 64 << 20
+
+// ExternalOp is a set operation performed by ExternalMerge.
+type ExternalOp int
+
+const (
+	// ExternalUnion is the union of all the sources, see ISet.Unions.
+	ExternalUnion ExternalOp = iota
+	// ExternalIntersection is the intersection of all the sources, see ISet.Intersections.
+	ExternalIntersection
+	// ExternalComplement is the first source minus all the others, see ISet.Complements.
+	ExternalComplement
+)
+
+// ExternalMerge computes a set operation over sets stored in files that may not fit in memory.
+// Each unsorted source is split into runs of at most ExternalOptions.MaxMemory bytes, which are
+// sorted in memory and spilled to temporary files; the runs and the sorted sources are then merged
+// as streams. The result is streamed in sorted file order, see SortedFile.
+// The returned stream must be closed, which removes the temporary files.
+// Examples:
+// st, err := ExternalMerge(ExternalIntersection, []ExternalSource{{"a.txt", FormatLines}, {"b.txt", FormatLines}}, nil)
+//
+//	for st.Next() {
+//	    fmt.Println(st.Elem())
+//	}
+//
+// err = st.Err()
+// st.Close()
+func ExternalMerge(op ExternalOp, srcs []ExternalSource, opts *ExternalOptions) (*ExternalStream, error) {
+	var o ExternalOptions
+	if opts != nil {
+		o = *opts
+	}
+	st := &ExternalStream{tempDir: o.TempDir}
+	if o.MaxMemory <= 0 {
+		o.MaxMemory = defaultExternalMaxMemory
+	}
+	inputs := make([]encStream, 0, len(srcs))
+	for _, src := range srcs {
+		in, err := st.open(src, &o)
+		if err != nil {
+			st.Close()
+			return nil, err
+		}
+		inputs = append(inputs, in)
+	}
+	switch {
+	case len(inputs) == 0:
+		st.src = newMergeStream(nil)
+	case op == ExternalUnion:
+		st.src = newMergeStream(inputs)
+	case op == ExternalIntersection:
+		st.src = &intersectStream{inputs: inputs}
+	case op == ExternalComplement:
+		st.src = &complementStream{a: inputs[0], b: newMergeStream(inputs[1:])}
+	default:
+		st.Close()
+		return nil, fmt.Errorf("go-set: ExternalMerge() err, unknown operation %d", op)
+	}
+	return st, nil
+}
+
+// ExternalUnions writes the union of the sources to dst, see ExternalMerge.
+func ExternalUnions(dst ExternalSource, opts *ExternalOptions, srcs ...ExternalSource) error {
+	return externalWrite(dst, ExternalUnion, srcs, opts)
+}
+
+// ExternalIntersections writes the intersection of the sources to dst, see ExternalMerge.
+func ExternalIntersections(dst ExternalSource, opts *ExternalOptions, srcs ...ExternalSource) error {
+	return externalWrite(dst, ExternalIntersection, srcs, opts)
+}
+
+// ExternalComplements writes src minus others to dst, see ExternalMerge.
+func ExternalComplements(dst ExternalSource, opts *ExternalOptions, src ExternalSource, others ...ExternalSource) error {
+	return externalWrite(dst, ExternalComplement, append([]ExternalSource{src}, others...), opts)
+}
+
+func externalWrite(dst ExternalSource, op ExternalOp, srcs []ExternalSource, opts *ExternalOptions) error {
+	st, err := ExternalMerge(op, srcs, opts)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	f, err := os.Create(dst.Path)
+	if err != nil {
+		return err
+	}
+	if err := st.WriteAs(f, dst.Format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ExternalStream is the sorted, duplicate free result of ExternalMerge.
+type ExternalStream struct {
+	tempDir string
+	src     encStream
+	elem    interface{}
+	err     error
+	cleanup []func() error
+}
+
+// Next advances to the next element, it returns false at the end of the stream or on error.
+func (s *ExternalStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	enc, ok, err := s.src.next()
+	if err == nil && ok {
+		s.elem, _, err = decodeElem(enc)
+	}
+	if err != nil {
+		s.err = err
+		return false
+	}
+	return ok
+}
+
+// Elem returns the current element.
+func (s *ExternalStream) Elem() interface{} {
+	return s.elem
+}
+
+// Err returns the error that stopped Next, if any.
+func (s *ExternalStream) Err() error {
+	return s.err
+}
+
+// Close releases the sources and removes the temporary files.
+func (s *ExternalStream) Close() error {
+	var err error
+	for _, fn := range s.cleanup {
+		if cerr := fn(); err == nil {
+			err = cerr
+		}
+	}
+	s.cleanup = nil
+	return err
+}
+
+// Iter returns an Iterator over the rest of the stream. Check Err once it is exhausted.
+func (s *ExternalStream) Iter() *Iterator {
+	iterator, ch, stopCh := newIterator()
+	go func() {
+		defer close(ch)
+		for s.Next() {
+			select {
+			case <-stopCh:
+				return
+			case ch <- s.Elem():
+			}
+		}
+	}()
+	return iterator
+}
+
+// WriteAs writes the rest of the stream to w in the given format.
+// FormatLines requires string elements.
+func (s *ExternalStream) WriteAs(w io.Writer, format ExternalFormat) error {
+	switch format {
+	case FormatSorted:
+		sw := NewSortedFileWriter(w, DefaultSortedFileBlockSize)
+		for s.Next() {
+			if err := sw.Add(s.Elem()); err != nil {
+				return err
+			}
+		}
+		if s.err != nil {
+			return s.err
+		}
+		return sw.Close()
+	case FormatLines:
+		bw := bufio.NewWriter(w)
+		for s.Next() {
+			str, ok := s.Elem().(string)
+			if !ok || strings.Contains(str, "\n") {
+				return fmt.Errorf("go-set: ExternalStream WriteAs() err, cannot write %#v as a line", s.Elem())
+			}
+			bw.WriteString(str)
+			if err := bw.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		if s.err != nil {
+			return s.err
+		}
+		return bw.Flush()
+	case FormatBinary:
+		// WriteSet starts with the element count, spool the elements to learn it.
+		tmp, err := ioutil.TempFile(s.tempDir, "go-set-external-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		bw := bufio.NewWriter(tmp)
+		var n uint64
+		for s.Next() {
+			if err := writeElem(bw, s.Elem(), nil); err != nil {
+				return err
+			}
+			n++
+		}
+		if s.err != nil {
+			return s.err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := w.Write(appendUvarint(nil, n)); err != nil {
+			return err
+		}
+		_, err = io.Copy(w, tmp)
+		return err
+	}
+	return fmt.Errorf("go-set: ExternalStream WriteAs() err, unknown format %d", format)
+}
+
+// open returns a sorted, duplicate free stream over src, sorting it externally if needed.
+func (s *ExternalStream) open(src ExternalSource, o *ExternalOptions) (encStream, error) {
+	if src.Format == FormatSorted {
+		f, err := OpenSortedFile(src.Path)
+		if err != nil {
+			return nil, err
+		}
+		s.cleanup = append(s.cleanup, f.Close)
+		return &sortedFileStream{f: f}, nil
+	}
+	in, err := os.Open(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	var runs []encStream
+	var chunk [][]byte
+	var size int
+	spill := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		run, err := s.writeRun(chunk, o.TempDir)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		chunk, size = nil, 0
+		return nil
+	}
+	err = readEncoded(bufio.NewReader(in), src.Format, func(enc []byte) error {
+		chunk = append(chunk, enc)
+		if size += len(enc) + 24; size >= o.MaxMemory {
+			return spill()
+		}
+		return nil
+	})
+	if err == nil {
+		err = spill()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("go-set: ExternalMerge() err, %s: %v", src.Path, err)
+	}
+	return newMergeStream(runs), nil
+}
+
+// writeRun sorts chunk and writes it, without duplicates, to a temporary file.
+func (s *ExternalStream) writeRun(chunk [][]byte, dir string) (encStream, error) {
+	sort.Slice(chunk, func(i, j int) bool { return bytes.Compare(chunk[i], chunk[j]) < 0 })
+	f, err := ioutil.TempFile(dir, "go-set-run-")
+	if err != nil {
+		return nil, err
+	}
+	s.cleanup = append(s.cleanup, func() error {
+		f.Close()
+		return os.Remove(f.Name())
+	})
+	bw := bufio.NewWriter(f)
+	for i, enc := range chunk {
+		if i > 0 && bytes.Equal(enc, chunk[i-1]) {
+			continue
+		}
+		bw.Write(appendUvarint(nil, uint64(len(enc))))
+		if _, err := bw.Write(enc); err != nil {
+			return nil, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &runStream{r: bufio.NewReader(f)}, nil
+}
+
+// readEncoded calls fn with the encoding of every element of an unsorted input.
+func readEncoded(r *bufio.Reader, format ExternalFormat, fn func(enc []byte) error) error {
+	switch format {
+	case FormatLines:
+		for {
+			line, err := r.ReadString('\n')
+			if err == io.EOF && line == "" {
+				return nil
+			}
+			if err != nil && err != io.EOF {
+				return err
+			}
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			enc, _ := appendElem(nil, line)
+			if err := fn(enc); err != nil {
+				return err
+			}
+		}
+	case FormatBinary:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			enc, err := readRaw(r)
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return err
+			}
+			if _, m, err := decodeElem(enc); err != nil || m != len(enc) {
+				return fmt.Errorf("go-set: decode err, corrupt element %d", i)
+			}
+			if err := fn(enc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %d", format)
+}
+
+// readRaw reads a length prefixed element without decoding it.
+func readRaw(r *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > maxElemSize {
+		return nil, fmt.Errorf("go-set: decode err, element of %d bytes is too large", l)
+	}
+	enc := make([]byte, l)
+	if _, err := io.ReadFull(r, enc); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return enc, nil
+}
+
+// encStream is a stream of encoded elements in ascending order without duplicates.
+type encStream interface {
+	// next returns the next element, false at the end of the stream.
+	next() ([]byte, bool, error)
+}
+
+type runStream struct {
+	r *bufio.Reader
+}
+
+func (s *runStream) next() ([]byte, bool, error) {
+	enc, err := readRaw(s.r)
+	if err == io.EOF {
+		return nil, false, nil
+	}
+	return enc, err == nil, err
+}
+
+type sortedFileStream struct {
+	f   *SortedFile
+	off int
+	end int
+}
+
+func (s *sortedFileStream) next() ([]byte, bool, error) {
+	if s.f.blocks == 0 {
+		return nil, false, nil
+	}
+	if s.end == 0 {
+		s.off, s.end = s.f.blockOffset(0), s.f.blockEnd(s.f.blocks-1)
+	}
+	if s.off >= s.end {
+		return nil, false, nil
+	}
+	enc, next, err := s.f.entry(s.off, s.end)
+	if err != nil {
+		s.f.fail(err)
+		return nil, false, s.f.Err()
+	}
+	s.off = next
+	return enc, true, nil
+}
+
+// mergeStream merges sorted streams into their sorted union.
+type mergeStream struct {
+	h    encHeap
+	init []encStream
+	last []byte
+}
+
+func newMergeStream(inputs []encStream) *mergeStream {
+	return &mergeStream{init: inputs}
+}
+
+type encHead struct {
+	enc []byte
+	src encStream
+}
+
+type encHeap []encHead
+
+func (h encHeap) Len() int            { return len(h) }
+func (h encHeap) Less(i, j int) bool  { return bytes.Compare(h[i].enc, h[j].enc) < 0 }
+func (h encHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *encHeap) Push(x interface{}) { *h = append(*h, x.(encHead)) }
+func (h *encHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func (m *mergeStream) next() ([]byte, bool, error) {
+	if m.init != nil {
+		for _, in := range m.init {
+			enc, ok, err := in.next()
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				m.h = append(m.h, encHead{enc: enc, src: in})
+			}
+		}
+		heap.Init(&m.h)
+		m.init = nil
+	}
+	for len(m.h) > 0 {
+		top := m.h[0]
+		enc, ok, err := top.src.next()
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			m.h[0].enc = enc
+			heap.Fix(&m.h, 0)
+		} else {
+			heap.Pop(&m.h)
+		}
+		if m.last != nil && bytes.Equal(top.enc, m.last) {
+			continue
+		}
+		m.last = top.enc
+		return top.enc, true, nil
+	}
+	return nil, false, nil
+}
+
+// intersectStream yields the elements present in every input.
+type intersectStream struct {
+	inputs []encStream
+	heads  [][]byte
+	done   bool
+}
+
+func (s *intersectStream) next() ([]byte, bool, error) {
+	if s.done {
+		return nil, false, nil
+	}
+	if s.heads == nil {
+		s.heads = make([][]byte, len(s.inputs))
+		for i := range s.inputs {
+			if err := s.advance(i); err != nil || s.done {
+				return nil, false, err
+			}
+		}
+	}
+	for {
+		max := s.heads[0]
+		for _, h := range s.heads[1:] {
+			if bytes.Compare(h, max) > 0 {
+				max = h
+			}
+		}
+		equal := true
+		for i := range s.heads {
+			for bytes.Compare(s.heads[i], max) < 0 {
+				if err := s.advance(i); err != nil || s.done {
+					return nil, false, err
+				}
+			}
+			if !bytes.Equal(s.heads[i], max) {
+				equal = false
+			}
+		}
+		if equal {
+			for i := range s.heads {
+				if err := s.advance(i); err != nil {
+					return nil, false, err
+				}
+			}
+			return max, true, nil
+		}
+	}
+}
+
+// advance moves input i to its next element, marking the stream done when it is exhausted.
+func (s *intersectStream) advance(i int) error {
+	enc, ok, err := s.inputs[i].next()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.done = true
+	}
+	s.heads[i] = enc
+	return nil
+}
+
+// complementStream yields the elements of a that are not in b.
+type complementStream struct {
+	a, b  encStream
+	bHead []byte
+	bOk   bool
+	init  bool
+}
+
+func (s *complementStream) next() ([]byte, bool, error) {
+	if !s.init {
+		var err error
+		if s.bHead, s.bOk, err = s.b.next(); err != nil {
+			return nil, false, err
+		}
+		s.init = true
+	}
+	for {
+		enc, ok, err := s.a.next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		for s.bOk && bytes.Compare(s.bHead, enc) < 0 {
+			if s.bHead, s.bOk, err = s.b.next(); err != nil {
+				return nil, false, err
+			}
+		}
+		if !s.bOk || !bytes.Equal(s.bHead, enc) {
+			return enc, true, nil
+		}
+	}
+}
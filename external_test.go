@@ -0,0 +1,157 @@
+package set
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestLines writes the elements of s as lines, each repeated and shuffled, and returns the path.
+func writeTestLines(t *testing.T, dir, name string, s ISet) ExternalSource {
+	t.Helper()
+	var lines []string
+	for _, elem := range s.ToSlice().Interface() {
+		lines = append(lines, elem.(string), elem.(string))
+	}
+	rand.Shuffle(len(lines), func(i, j int) { lines[i], lines[j] = lines[j], lines[i] })
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\r\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return ExternalSource{Path: path, Format: FormatLines}
+}
+
+func newTestStrings(from, to int) ISet {
+	s := NewThreadUnsafeSet()
+	for i := from; i < to; i++ {
+		s.Adds(fmt.Sprintf("elem-%d", i))
+	}
+	return s
+}
+
+func TestExternalMerge(t *testing.T) {
+	dir := t.TempDir()
+	a, b, c := newTestStrings(0, 500), newTestStrings(250, 750), newTestStrings(400, 1000)
+	srcs := []ExternalSource{
+		writeTestLines(t, dir, "a.txt", a),
+		writeTestLines(t, dir, "b.txt", b),
+		writeTestLines(t, dir, "c.txt", c),
+	}
+	// A tiny memory budget forces many sorted runs per input.
+	opts := &ExternalOptions{TempDir: dir, MaxMemory: 1024}
+	tests := []struct {
+		name string
+		op   ExternalOp
+		want ISet
+	}{
+		{name: "union", op: ExternalUnion, want: a.Unions(b, c)},
+		{name: "intersection", op: ExternalIntersection, want: a.Intersections(b, c)},
+		{name: "complement", op: ExternalComplement, want: a.Complements(b, c)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := ExternalMerge(tt.op, srcs, opts)
+			if err != nil {
+				t.Fatalf("ExternalMerge() error = %v", err)
+			}
+			got := NewThreadUnsafeSet()
+			var last []byte
+			for st.Next() {
+				enc, _ := appendElem(nil, st.Elem())
+				if last != nil && bytes.Compare(last, enc) >= 0 {
+					t.Errorf("ExternalMerge() %v after %v, not sorted", st.Elem(), last)
+				}
+				last = enc
+				got.Adds(st.Elem())
+			}
+			if err := st.Err(); err != nil {
+				t.Errorf("Err() = %v", err)
+			}
+			if err := st.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ExternalMerge() = %v elements, want %v", got.Cardinality(), tt.want.Cardinality())
+			}
+		})
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "go-set-*")); len(files) != 0 {
+		t.Errorf("Close() left %v temporary files", len(files))
+	}
+}
+
+func TestExternalMerge_Formats(t *testing.T) {
+	dir := t.TempDir()
+	a, b := NewSet(1, 2, 3, "x", 2.5), NewSet(2, 3, 4, "x")
+
+	binPath := filepath.Join(dir, "a.bin")
+	f, _ := os.Create(binPath)
+	WriteSet(f, a)
+	f.Close()
+	sortedPath := filepath.Join(dir, "b.sorted")
+	BuildSortedFile(sortedPath, b)
+	srcs := []ExternalSource{{Path: binPath, Format: FormatBinary}, {Path: sortedPath, Format: FormatSorted}}
+
+	out := ExternalSource{Path: filepath.Join(dir, "out.bin"), Format: FormatBinary}
+	if err := ExternalIntersections(out, nil, srcs...); err != nil {
+		t.Fatalf("ExternalIntersections() error = %v", err)
+	}
+	got := NewSet()
+	f, _ = os.Open(out.Path)
+	err := ReadSet(f, got)
+	f.Close()
+	if want := NewSet(2, 3, "x"); err != nil || !got.Equal(want) {
+		t.Errorf("ExternalIntersections() = %v, %v, want %v", got, err, want)
+	}
+
+	out = ExternalSource{Path: filepath.Join(dir, "out.sorted"), Format: FormatSorted}
+	if err := ExternalUnions(out, nil, srcs...); err != nil {
+		t.Fatalf("ExternalUnions() error = %v", err)
+	}
+	sf, err := OpenSortedFile(out.Path)
+	if err != nil {
+		t.Fatalf("OpenSortedFile() error = %v", err)
+	}
+	defer sf.Close()
+	if want := a.Unions(b); !sf.Equal(want) {
+		t.Errorf("ExternalUnions() = %v, want %v", sf, want)
+	}
+
+	out = ExternalSource{Path: filepath.Join(dir, "out.txt"), Format: FormatLines}
+	if err := ExternalComplements(out, nil, srcs[0], srcs[1]); err == nil {
+		t.Errorf("ExternalComplements() error = %v, want an error for non-string lines", err)
+	}
+	texts := []ExternalSource{writeTestLines(t, dir, "c.txt", NewSet("a", "b", "c")), writeTestLines(t, dir, "d.txt", NewSet("b"))}
+	if err := ExternalComplements(out, nil, texts[0], texts[1]); err != nil {
+		t.Fatalf("ExternalComplements() error = %v", err)
+	}
+	data, _ := ioutil.ReadFile(out.Path)
+	if got, want := string(data), "a\nc\n"; got != want {
+		t.Errorf("ExternalComplements() = %q, want %q", got, want)
+	}
+}
+
+func TestExternalStream_Iter(t *testing.T) {
+	dir := t.TempDir()
+	src := writeTestLines(t, dir, "a.txt", newTestStrings(0, 100))
+	st, err := ExternalMerge(ExternalUnion, []ExternalSource{src}, nil)
+	if err != nil {
+		t.Fatalf("ExternalMerge() error = %v", err)
+	}
+	defer st.Close()
+	got := NewSet()
+	for elem := range st.Iter().C {
+		got.Adds(elem)
+	}
+	if want := newTestStrings(0, 100); !got.Equal(want) || st.Err() != nil {
+		t.Errorf("Iter() = %v elements, %v, want %v", got.Cardinality(), st.Err(), want.Cardinality())
+	}
+	if _, err := ExternalMerge(ExternalUnion, []ExternalSource{{Path: filepath.Join(dir, "missing")}}, nil); err == nil {
+		t.Errorf("ExternalMerge() error = %v, want an error", err)
+	}
+}
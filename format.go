@@ -0,0 +1,251 @@
+package set
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// elemRank orders elements of different kinds: nil, booleans, numbers, strings, then everything else.
+func elemRank(v reflect.Value) int {
+	if !v.IsValid() {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return 1
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return 2
+	case reflect.String:
+		return 3
+	}
+	return 4
+}
+
+// compareNumbers compares two numeric values exactly when both are integers. NaN sorts first.
+func compareNumbers(a, b reflect.Value) int {
+	af, aFloat := asFloat(a)
+	bf, bFloat := asFloat(b)
+	if aFloat || bFloat {
+		switch {
+		case math.IsNaN(af) && math.IsNaN(bf):
+			return 0
+		case math.IsNaN(af) || af < bf:
+			return -1
+		case math.IsNaN(bf) || af > bf:
+			return 1
+		}
+		return 0
+	}
+	aNeg, aMag := magnitude(a)
+	bNeg, bMag := magnitude(b)
+	switch {
+	case aNeg != bNeg:
+		if aNeg {
+			return -1
+		}
+		return 1
+	case aMag == bMag:
+		return 0
+	case (aMag < bMag) != aNeg:
+		return -1
+	}
+	return 1
+}
+
+func asFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	}
+	return float64(v.Uint()), false
+}
+
+// magnitude splits an integer into its sign and absolute value.
+func magnitude(v reflect.Value) (neg bool, mag uint64) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if x := v.Int(); x < 0 {
+			return true, uint64(-(x + 1)) + 1
+		} else {
+			return false, uint64(x)
+		}
+	}
+	return false, v.Uint()
+}
+
+// compareElems orders elements for printing: by kind, then by value, then by type name,
+// falling back to the printed value for elements that have no natural order.
+func compareElems(a, b interface{}) int {
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	ar, br := elemRank(av), elemRank(bv)
+	if ar != br {
+		return ar - br
+	}
+	var c int
+	switch ar {
+	case 0:
+		return 0
+	case 1:
+		if av.Bool() != bv.Bool() {
+			c = 1
+			if bv.Bool() {
+				c = -1
+			}
+		}
+	case 2:
+		c = compareNumbers(av, bv)
+	case 3:
+		c = strings.Compare(av.String(), bv.String())
+	default:
+		c = strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(av.Type().String(), bv.Type().String())
+}
+
+func sortElems(elems []interface{}) {
+	sort.SliceStable(elems, func(i, j int) bool { return compareElems(elems[i], elems[j]) < 0 })
+}
+
+// formatElems prints the elements sorted, as in {1,2,3}, truncated after limit elements if limit >= 0.
+// withTypes prints each element with its type, as in {int(1),string(a)}.
+func formatElems(elems []interface{}, limit int, withTypes bool) string {
+	sortElems(elems)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, elem := range elems {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if i == limit {
+			fmt.Fprintf(&b, "... %d more", len(elems)-limit)
+			break
+		}
+		if withTypes {
+			fmt.Fprintf(&b, "%T(%+v)", elem, elem)
+		} else if str, ok := elem.(string); ok {
+			b.WriteString(quoteElem(str))
+		} else {
+			fmt.Fprintf(&b, "%v", elem)
+		}
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatString implements String for sets.
+func formatString(s ISet) string {
+	return formatElems(s.ToSlice().Interface(), -1, false)
+}
+
+// goSyntax prints an element as a Go expression evaluating to it. Literals of basic kinds are converted
+// to the type of the element, unless it is the default type of the literal, as in int8(1) or time.Duration(1000).
+func goSyntax(elem interface{}) string {
+	if elem == nil {
+		return "nil"
+	}
+	if f, ok := elem.(Frozen); ok {
+		return "set.NewFrozen(" + goSyntaxElems(f.ToSlice().Interface()) + ")"
+	}
+	v := reflect.ValueOf(elem)
+	var lit string
+	var defaultType reflect.Type
+	switch v.Kind() {
+	case reflect.Bool:
+		lit, defaultType = strconv.FormatBool(v.Bool()), reflect.TypeOf(false)
+	case reflect.String:
+		lit, defaultType = strconv.Quote(v.String()), reflect.TypeOf("")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		lit, defaultType = strconv.FormatInt(v.Int(), 10), reflect.TypeOf(0)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		lit = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		x := v.Float()
+		switch {
+		case math.IsNaN(x):
+			lit = "math.NaN()"
+		case math.IsInf(x, 0):
+			lit = fmt.Sprintf("math.Inf(%d)", int(math.Copysign(1, x)))
+		default:
+			lit = strconv.FormatFloat(x, 'g', -1, v.Type().Bits())
+			if !strings.ContainsAny(lit, ".eE") {
+				lit += ".0" // so it is not an int
+			}
+		}
+		defaultType = reflect.TypeOf(0.0)
+	case reflect.Complex64, reflect.Complex128:
+		lit, defaultType = strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()), reflect.TypeOf(0i)
+	default:
+		return fmt.Sprintf("%#v", elem)
+	}
+	if v.Type() == defaultType {
+		return lit
+	}
+	return v.Type().String() + "(" + lit + ")"
+}
+
+func goSyntaxElems(elems []interface{}) string {
+	sortElems(elems)
+	parts := make([]string, len(elems))
+	for i, elem := range elems {
+		parts[i] = goSyntax(elem)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSet implements fmt.Formatter for sets:
+// %v and %s print the sorted elements, as in {1,2,3}, truncated after as many elements as the precision,
+// as in %.3v printing {1,2,3,... 9997 more}, which Parse rejects;
+// %+v also prints their types, as in {int(1),string(a)};
+// %#v prints Go syntax calling ctor, as in set.NewSet(1, "a"), never truncated;
+// other verbs are applied to each element, as in %x.
+func formatSet(f fmt.State, verb rune, ctor string, s ISet) {
+	elems := s.ToSlice().Interface()
+	limit := -1
+	if p, ok := f.Precision(); ok {
+		limit = p
+	}
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		fmt.Fprintf(f, "set.%s(%s)", ctor, goSyntaxElems(elems))
+	case verb == 'v' || verb == 's':
+		io.WriteString(f, formatElems(elems, limit, verb == 'v' && f.Flag('+')))
+	default:
+		sortElems(elems)
+		format := elemVerb(f, verb)
+		parts := make([]string, len(elems))
+		for i, elem := range elems {
+			parts[i] = fmt.Sprintf(format, elem)
+		}
+		fmt.Fprintf(f, "{%s}", strings.Join(parts, ","))
+	}
+}
+
+// elemVerb rebuilds the directive being formatted, flags, width and precision included, as in %08x.
+func elemVerb(f fmt.State, verb rune) string {
+	b := []byte{'%'}
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			b = append(b, byte(flag))
+		}
+	}
+	if w, ok := f.Width(); ok {
+		b = strconv.AppendInt(b, int64(w), 10)
+	}
+	if p, ok := f.Precision(); ok {
+		b = append(b, '.')
+		b = strconv.AppendInt(b, int64(p), 10)
+	}
+	return string(append(b, string(verb)...))
+}
@@ -0,0 +1,87 @@
+package set
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestISet_String(t *testing.T) {
+	sets := map[string]func(elems ...interface{}) ISet{
+		"NewThreadUnsafeSet": NewThreadUnsafeSet,
+		"NewSet":             NewSet,
+		"NewFrozen":          func(elems ...interface{}) ISet { return NewFrozen(elems...) },
+		"NewShardedSet":      func(elems ...interface{}) ISet { return NewShardedSet(4, elems...) },
+		"NewLockFreeSet":     func(elems ...interface{}) ISet { return NewLockFreeSet(elems...) },
+	}
+	tests := []struct {
+		elems []interface{}
+		want  string
+	}{
+		{nil, "{}"},
+		{[]interface{}{3, 1, 2}, "{1,2,3}"},
+		{[]interface{}{"b", 10, "a", -1, 2.5, true}, "{true,-1,2.5,10,a,b}"},
+		{[]interface{}{uint64(math.MaxUint64), int64(math.MinInt64), 0}, "{-9223372036854775808,0,18446744073709551615}"},
+		{[]interface{}{int8(1), 1, uint(1)}, "{1,1,1}"},
+	}
+	for name, newSet := range sets {
+		for _, tt := range tests {
+			if got := newSet(tt.elems...).String(); got != tt.want {
+				t.Errorf("%s(%v).String() = %v, want %v", name, tt.elems, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestISet_Truncate(t *testing.T) {
+	s := NewSet()
+	for i := 0; i < 10000; i++ {
+		s.Adds(i)
+	}
+	if got := s.String(); strings.Contains(got, "more") || !strings.HasSuffix(got, ",9999}") {
+		t.Errorf("String() = ...%v, want every element", got[len(got)-20:])
+	}
+	if got, want := fmt.Sprintf("%.3v", s), "{0,1,2,... 9997 more}"; got != want {
+		t.Errorf("Sprintf(%%.3v) = %v, want %v", got, want)
+	}
+	if got, want := fmt.Sprintf("%.0v", s), "{... 10000 more}"; got != want {
+		t.Errorf("Sprintf(%%.0v) = %v, want %v", got, want)
+	}
+	if got, want := fmt.Sprintf("%.3v", NewSet(1, 2, 3)), "{1,2,3}"; got != want {
+		t.Errorf("Sprintf(%%.3v) = %v, want %v", got, want)
+	}
+}
+
+func TestISet_Format(t *testing.T) {
+	tests := []struct {
+		format string
+		s      ISet
+		want   string
+	}{
+		{"%v", NewSet(2, "a", 1), "{1,2,a}"},
+		{"%s", NewSet(2, 1), "{1,2}"},
+		{"%+v", NewSet(2, "a", int8(1)), "{int8(1),int(2),string(a)}"},
+		{"%x", NewSet(255, 16), "{10,ff}"},
+		{"%#v", NewSet(), "set.NewSet()"},
+		{"%#v", NewSet(2, "a", 1.0, int8(-1), uint(3), 2.5, true, nil), `set.NewSet(nil, true, int8(-1), 1.0, 2, 2.5, uint(3), "a")`},
+		{"%#v", NewThreadUnsafeSet(1), "set.NewThreadUnsafeSet(1)"},
+		{"%#v", NewFrozen(1, NewFrozen("x")), `set.NewFrozen(1, set.NewFrozen("x"))`},
+		{"%#v", NewSet(math.Inf(-1), math.NaN(), float32(0.5)), "set.NewSet(math.NaN(), math.Inf(-1), float32(0.5))"},
+		{"%v", NewCOWSet(3, 1), "{1,3}"},
+		{"%08x", NewSet(255, 16), "{00000010,000000ff}"},
+		{"%-4d|", NewSet(1, 22), "{1   ,22  }|"},
+		{"%+.1f", NewSet(1.25, -2.0), "{-2.0,+1.2}"},
+		{"%#v", NewSet(time.Second, -time.Millisecond), "set.NewSet(time.Duration(-1000000), time.Duration(1000000000))"},
+		{"%#v", NewSet(float32(math.NaN()), float32(math.Inf(1)), float32(2)), "set.NewSet(float32(math.NaN()), float32(2.0), float32(math.Inf(1)))"},
+		{"%#v", NewSet(complex64(1+2i), 3i, myString("b")), `set.NewSet(set.myString("b"), (0+3i), complex64((1+2i)))`},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, tt.s); got != tt.want {
+			t.Errorf("Sprintf(%q) = %v, want %v", tt.format, got, tt.want)
+		}
+	}
+}
+
+type myString string
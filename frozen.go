@@ -0,0 +1,226 @@
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// NewFrozen returns an immutable set holding elems, see Freeze. It panics if an element cannot be frozen.
+func NewFrozen(elems ...interface{}) Frozen {
+	f, err := Freeze(NewThreadUnsafeSet(elems...))
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Freeze returns an immutable copy of s. nil, booleans, numbers, strings and Frozen sets can be frozen,
+// other element types return an error.
+func Freeze(s ISet) (Frozen, error) {
+	elems := s.ToSlice().Interface()
+	encs := make([][]byte, len(elems))
+	for i, elem := range elems {
+		enc, err := appendElem(nil, canonicalElem(elem))
+		if err != nil {
+			return Frozen{}, err
+		}
+		encs[i] = enc
+	}
+	sort.Slice(encs, func(i, j int) bool { return bytes.Compare(encs[i], encs[j]) < 0 })
+	var b []byte
+	for _, enc := range encs {
+		b = append(appendUvarint(b, uint64(len(enc))), enc...)
+	}
+	return Frozen{n: len(encs), enc: string(b)}, nil
+}
+
+// canonicalElem returns the representation of elem whose encoding a Frozen set stores:
+// -0 and +0 are the same element, so they must encode alike.
+func canonicalElem(elem interface{}) interface{} {
+	switch x := elem.(type) {
+	case float32:
+		if x == 0 {
+			return float32(0)
+		}
+	case float64:
+		if x == 0 {
+			return float64(0)
+		}
+	case complex64:
+		return complex(canonicalElem(real(x)).(float32), canonicalElem(imag(x)).(float32))
+	case complex128:
+		return complex(canonicalElem(real(x)).(float64), canonicalElem(imag(x)).(float64))
+	}
+	return elem
+}
+
+// frozenFromEncoding validates the encoding of a Frozen set read from outside.
+func frozenFromEncoding(enc string, n uint64) (Frozen, error) {
+	f := Frozen{n: int(n), enc: enc}
+	var count uint64
+	var last []byte
+	var err error
+	f.each(func(b []byte) bool {
+		if _, l, e := decodeElem(b); e != nil || l != len(b) {
+			err = errShortElem
+		} else if last != nil && bytes.Compare(last, b) >= 0 {
+			err = errors.New("go-set: decode err, frozen set elements out of order")
+		}
+		last = b
+		count++
+		return err == nil
+	})
+	if err == nil && (count != n || f.n < 0) {
+		err = fmt.Errorf("go-set: decode err, frozen set of %d elements, want %d", count, n)
+	}
+	if err != nil {
+		return Frozen{}, err
+	}
+	return f, nil
+}
+
+// Frozen is an immutable set. Unlike the other sets it is a comparable value: frozen sets holding the same
+// elements are ==, so they can be elements of other sets, Frozen ones included, or map keys.
+// Its mutating methods panic, and its algebra returns new mutable sets, see NewSet.
+// Frozen sets store their elements encoded, and decode them on use: they are meant for small sets.
+// The zero value is the empty set.
+// Examples:
+// s := NewSet(NewFrozen(1, 2), NewFrozen(3))
+// s.Contains(NewFrozen(2, 1)) // true
+type Frozen struct {
+	n   int
+	enc string // the sorted encodings of the elements, each prefixed with its length
+}
+
+// each calls fn with the encoding of every element in order, until it returns false.
+func (f Frozen) each(fn func(enc []byte) bool) {
+	for p := []byte(f.enc); len(p) > 0; {
+		l, n := binary.Uvarint(p)
+		if n <= 0 || uint64(len(p)-n) < l {
+			fn(nil) // only sets read by frozenFromEncoding can be corrupted, it rejects nil
+			return
+		}
+		if !fn(p[n : n+int(l)]) {
+			return
+		}
+		p = p[n+int(l):]
+	}
+}
+
+func (f Frozen) elems() []interface{} {
+	result := make([]interface{}, 0, f.n)
+	f.each(func(enc []byte) bool {
+		elem, _, _ := decodeElem(enc)
+		result = append(result, elem)
+		return true
+	})
+	return result
+}
+
+func (f Frozen) Empty() bool {
+	return f.n == 0
+}
+
+func (f Frozen) Singleton() bool {
+	return f.n == 1
+}
+
+func (f Frozen) Cardinality() int {
+	return f.n
+}
+
+func (f Frozen) ToSlice() ISlice {
+	return Slice(f.elems())
+}
+
+func (f Frozen) Adds(...interface{}) bool {
+	panic(errReadOnly)
+}
+
+func (f Frozen) Removes(...interface{}) bool {
+	panic(errReadOnly)
+}
+
+func (f Frozen) Clear() {
+	panic(errReadOnly)
+}
+
+func (f Frozen) Pop() interface{} {
+	panic(errReadOnly)
+}
+
+func (f Frozen) IsSub(other ISet) bool {
+	if f.n > other.Cardinality() {
+		return false
+	}
+	return other.Contains(f.elems()...)
+}
+
+// Unions returns a new mutable set, see NewSet.
+func (f Frozen) Unions(others ...ISet) ISet {
+	return f.Clone().Unions(others...)
+}
+
+// Intersections returns a new mutable set, see NewSet.
+func (f Frozen) Intersections(others ...ISet) ISet {
+	return f.Clone().Intersections(others...)
+}
+
+// Complements returns a new mutable set, see NewSet.
+func (f Frozen) Complements(others ...ISet) ISet {
+	return f.Clone().Complements(others...)
+}
+
+func (f Frozen) Contains(elems ...interface{}) bool {
+	for i := 0; i < len(elems); i++ {
+		enc, err := appendElem(nil, canonicalElem(elems[i]))
+		if err != nil {
+			return false
+		}
+		var found bool
+		f.each(func(b []byte) bool {
+			c := bytes.Compare(b, enc)
+			found = c == 0
+			return c < 0
+		})
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a mutable copy of the set, see NewSet.
+func (f Frozen) Clone() ISet {
+	return NewSet(f.elems()...)
+}
+
+func (f Frozen) Equal(other ISet) bool {
+	if o, ok := other.(Frozen); ok {
+		return f == o
+	}
+	if other.Cardinality() != f.n {
+		return false
+	}
+	return f.Contains(other.ToSlice().Interface()...)
+}
+
+// String lists the elements in a deterministic order.
+func (f Frozen) String() string {
+	return formatString(f)
+}
+
+func (f Frozen) Format(st fmt.State, verb rune) {
+	formatSet(st, verb, "NewFrozen", f)
+}
+
+func (f Frozen) Hash() uint64 {
+	var h setHash
+	for _, elem := range f.elems() {
+		h.add(elem)
+	}
+	return h.sum64()
+}
@@ -0,0 +1,72 @@
+package set
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestFrozen(t *testing.T) {
+	a, b := NewFrozen(1, 2, "x"), NewFrozen("x", 2, 1)
+	if a != b || !a.Equal(b) || a.Equal(NewFrozen(1, 2)) {
+		t.Errorf("%v == %v = %v, want true", a, b, a == b)
+	}
+	if NewFrozen(0.0) != NewFrozen(math.Copysign(0, -1)) {
+		t.Errorf("NewFrozen(0) != NewFrozen(-0)")
+	}
+	if !a.Contains(1, "x") || a.Contains(3) || a.Contains([]int{1}) || a.Cardinality() != 3 || a.Empty() || (Frozen{}).Cardinality() != 0 {
+		t.Errorf("Contains(), Cardinality() of %v", a)
+	}
+	if !a.Equal(NewSet(1, 2, "x")) || !NewSet(1, 2, "x").Equal(a) || !NewFrozen(2).IsSub(a) || !NewFrozen(2).Singleton() {
+		t.Errorf("Equal(), IsSub() of %v", a)
+	}
+	if got, want := a.Unions(NewSet(3)), NewSet(1, 2, 3, "x"); !got.Equal(want) {
+		t.Errorf("Unions() = %v, want %v", got, want)
+	}
+	if got := a.Clone(); !got.Adds(3) || got.Cardinality() != 4 {
+		t.Errorf("Clone() = %v, want a mutable copy", got)
+	}
+	if got, want := NewFrozen(3, 1, 2).String(), "{1,2,3}"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+	defer func() {
+		if r := recover(); r != errReadOnly {
+			t.Errorf("Adds() panic = %v, want %v", r, errReadOnly)
+		}
+	}()
+	a.Adds(3)
+}
+
+func TestFrozen_Nested(t *testing.T) {
+	s := NewSet(NewFrozen(1, 2), NewFrozen(3))
+	if !s.Contains(NewFrozen(2, 1)) || s.Adds(NewFrozen(3)) {
+		t.Errorf("Contains() = %v, want frozen sets compared by value", s)
+	}
+	m := map[Frozen]string{NewFrozen("a", "b"): "ab"}
+	if m[NewFrozen("b", "a")] != "ab" {
+		t.Errorf("map[Frozen] lookup failed")
+	}
+	nested := NewFrozen(NewFrozen(1), NewFrozen(NewFrozen()))
+	if !nested.Contains(NewFrozen(1), NewFrozen(Frozen{})) {
+		t.Errorf("Contains() = false, want nested frozen sets")
+	}
+	if _, err := Freeze(NewSet(struct{}{})); err == nil {
+		t.Errorf("Freeze() error = %v, want an error", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSet(&buf, s); err != nil {
+		t.Fatalf("WriteSet() error = %v", err)
+	}
+	got := NewSet()
+	if err := ReadSet(&buf, got); err != nil || !got.Equal(s) {
+		t.Errorf("ReadSet() = %v, %v, want %v", got, err, s)
+	}
+	enc, _ := appendElem(nil, nested)
+	if _, _, err := decodeElem(enc[:len(enc)-1]); err == nil {
+		t.Errorf("decodeElem() error = %v, want an error", err)
+	}
+	if _, _, err := decodeElem(append([]byte{tagFrozen, 2, 4, 2, byte(tagInt), 2, 0}, 0)); err == nil {
+		t.Errorf("decodeElem() error = %v, want an error for a corrupted frozen set", err)
+	}
+}
@@ -0,0 +1,80 @@
+//go:build go1.18
+// +build go1.18
+
+package set
+
+import (
+	"fmt"
+	"reflect"
+)
+
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+// ToSliceOf returns the elements of s as a []T, for any element type: named types, structs, arrays,
+// time.Time... An element is converted if it is a T, or implements T if T is an interface type.
+// The error is a *ConversionError listing every element that is not.
+// Examples:
+// ToSliceOf[time.Duration](NewSet(time.Second, time.Minute)) return []time.Duration{time.Second, time.Minute}, nil
+// ToSliceOf[int](NewSet(1, "a")) return nil, go-set: Slice ToSliceOf[int]() err, 1 invalid values: [1]="a"
+func ToSliceOf[T any](s ISet) ([]T, error) {
+	elems := s.ToSlice().Interface()
+	c := conversion{method: "ToSliceOf[" + typeName[T]() + "]"}
+	result := make([]T, 0, len(elems))
+	for i, elem := range elems {
+		v, ok := elem.(T)
+		if !ok {
+			c.reject(i, elem)
+			continue
+		}
+		result = append(result, v)
+	}
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MustSliceOf is like ToSliceOf but panics if an element is not a T.
+func MustSliceOf[T any](s ISet) []T {
+	result, err := ToSliceOf[T](s)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// ToMapOf returns the elements of s as a map[T]struct{}, see ToSliceOf.
+func ToMapOf[T comparable](s ISet) (map[T]struct{}, error) {
+	elems, err := ToSliceOf[T](s)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[T]struct{}, len(elems))
+	for _, elem := range elems {
+		result[elem] = struct{}{}
+	}
+	return result, nil
+}
+
+// ToKeyedMapOf returns the elements of s as a map from key(elem) to elem, to index elements
+// such as structs by one of their fields, see ToSliceOf. It returns an error if two elements have the same key.
+// Examples:
+// ToKeyedMapOf(NewSet(User{ID: 1}, User{ID: 2}), func(u User) int { return u.ID })
+// return map[int]User{1: {ID: 1}, 2: {ID: 2}}, nil
+func ToKeyedMapOf[T any, K comparable](s ISet, key func(T) K) (map[K]T, error) {
+	elems, err := ToSliceOf[T](s)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[K]T, len(elems))
+	for _, elem := range elems {
+		k := key(elem)
+		if other, ok := result[k]; ok {
+			return nil, fmt.Errorf("go-set: ToKeyedMapOf() err, %+v and %+v have the same key %+v", other, elem, k)
+		}
+		result[k] = elem
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+//go:build go1.18
+// +build go1.18
+
+package set
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type testName string
+
+func TestToSliceOf(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	times, err := ToSliceOf[time.Time](NewSet(now))
+	if err != nil || !reflect.DeepEqual(times, []time.Time{now}) {
+		t.Errorf("ToSliceOf[time.Time]() = %v, %v", times, err)
+	}
+	ids, err := ToSliceOf[[2]byte](NewSet([2]byte{1, 2}))
+	if err != nil || !reflect.DeepEqual(ids, [][2]byte{{1, 2}}) {
+		t.Errorf("ToSliceOf[[2]byte]() = %v, %v", ids, err)
+	}
+	names := MustSliceOf[testName](NewSet(testName("b"), testName("a")))
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	if !reflect.DeepEqual(names, []testName{"a", "b"}) {
+		t.Errorf("MustSliceOf[testName]() = %v", names)
+	}
+	stringers, err := ToSliceOf[fmt.Stringer](NewSet(time.Second))
+	if err != nil || len(stringers) != 1 || stringers[0].String() != "1s" {
+		t.Errorf("ToSliceOf[fmt.Stringer]() = %v, %v", stringers, err)
+	}
+
+	var ce *ConversionError
+	if _, err := ToSliceOf[testName](NewSet("a", testName("b"), 1)); !errors.As(err, &ce) || len(ce.Invalid) != 2 || ce.Method != "ToSliceOf[set.testName]" {
+		t.Errorf("ToSliceOf[testName]() error = %v, want 2 invalid values", err)
+	}
+	defer func() {
+		if err, _ := recover().(error); !errors.As(err, &ce) {
+			t.Errorf("MustSliceOf() panic = %v, want a *ConversionError", err)
+		}
+	}()
+	MustSliceOf[int](NewSet("a"))
+}
+
+func TestToMapOf(t *testing.T) {
+	got, err := ToMapOf[int](NewSet(1, 2))
+	if want := map[int]struct{}{1: {}, 2: {}}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ToMapOf() = %v, %v, want %v", got, err, want)
+	}
+	if _, err := ToMapOf[int](NewSet(1, "a")); err == nil {
+		t.Errorf("ToMapOf() error = %v, want an error", err)
+	}
+
+	type user struct {
+		ID   int
+		Name string
+	}
+	byID := func(u user) int { return u.ID }
+	users, err := ToKeyedMapOf(NewSet(user{1, "a"}, user{2, "b"}), byID)
+	if want := map[int]user{1: {1, "a"}, 2: {2, "b"}}; err != nil || !reflect.DeepEqual(users, want) {
+		t.Errorf("ToKeyedMapOf() = %v, %v, want %v", users, err, want)
+	}
+	if _, err := ToKeyedMapOf(NewSet(user{1, "a"}, user{1, "b"}), byID); err == nil {
+		t.Errorf("ToKeyedMapOf() error = %v, want an error for a duplicate key", err)
+	}
+}
@@ -0,0 +1,164 @@
+package set
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// fnv64 is an allocation free FNV-1a hash.
+type fnv64 uint64
+
+func newFnv64() fnv64 {
+	return fnvOffset64
+}
+
+func (h *fnv64) writeByte(b byte) {
+	*h ^= fnv64(b)
+	*h *= fnvPrime64
+}
+
+func (h *fnv64) writeUint64(x uint64) {
+	for i := 0; i < 8; i++ {
+		h.writeByte(byte(x))
+		x >>= 8
+	}
+}
+
+func (h *fnv64) writeString(s string) {
+	h.writeUint64(uint64(len(s)))
+	for i := 0; i < len(s); i++ {
+		h.writeByte(s[i])
+	}
+}
+
+func (h *fnv64) writeFloat64(f float64) {
+	if f == 0 {
+		f = 0 // +0 and -0 compare equal, so they must hash equally.
+	}
+	h.writeUint64(math.Float64bits(f))
+}
+
+// sum returns the hash finalized with the splitmix64 mixer, so the low bits are usable as a bucket index.
+func (h fnv64) sum() uint64 {
+	x := uint64(h)
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// setHash accumulates an order independent hash of the elements of a set: the sum of their hashes,
+// so any iteration order gives the same result.
+type setHash struct {
+	sum, n uint64
+}
+
+func (h *setHash) add(elem interface{}) {
+	h.addHash(hashOf(elem))
+}
+
+func (h *setHash) addHash(x uint64) {
+	h.sum += x
+	h.n++
+}
+
+func (h setHash) sum64() uint64 {
+	f := newFnv64()
+	f.writeUint64(h.sum)
+	f.writeUint64(h.n)
+	return f.sum()
+}
+
+// hashOf returns a 64-bit hash of a comparable value: values that are equal according to == hash equally.
+// Strings, numbers, booleans and arrays or structs made of them hash identically in every process,
+// pointers and channels hash by address.
+// It panics if v is not comparable, just like using v as a map key does.
+func hashOf(v interface{}) uint64 {
+	h := newFnv64()
+	switch x := v.(type) {
+	case nil:
+		h.writeByte(0)
+	case string:
+		h.writeString(x)
+	case int:
+		h.writeUint64(uint64(x))
+	case int8:
+		h.writeUint64(uint64(x))
+	case int16:
+		h.writeUint64(uint64(x))
+	case int32:
+		h.writeUint64(uint64(x))
+	case int64:
+		h.writeUint64(uint64(x))
+	case uint:
+		h.writeUint64(uint64(x))
+	case uint8:
+		h.writeUint64(uint64(x))
+	case uint16:
+		h.writeUint64(uint64(x))
+	case uint32:
+		h.writeUint64(uint64(x))
+	case uint64:
+		h.writeUint64(x)
+	case bool:
+		if x {
+			h.writeByte(1)
+		} else {
+			h.writeByte(0)
+		}
+	default:
+		hashValue(&h, reflect.ValueOf(v))
+	}
+	return h.sum()
+}
+
+func hashValue(h *fnv64, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		h.writeByte(0)
+	case reflect.Bool:
+		if v.Bool() {
+			h.writeByte(1)
+		} else {
+			h.writeByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.writeUint64(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		h.writeUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		h.writeFloat64(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		h.writeFloat64(real(c))
+		h.writeFloat64(imag(c))
+	case reflect.String:
+		h.writeString(v.String())
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		h.writeUint64(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			h.writeByte(0)
+		} else {
+			hashValue(h, v.Elem())
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			hashValue(h, v.Field(i))
+		}
+	default:
+		panic(fmt.Sprintf("go-set: hash of unhashable type %s", v.Type()))
+	}
+}
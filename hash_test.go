@@ -0,0 +1,72 @@
+package set
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_hashOf(t *testing.T) {
+	type pair struct {
+		A int
+		B string
+	}
+	x := 1
+	tests := []struct {
+		name string
+		a, b interface{}
+	}{
+		{name: "int", a: 1, b: 1},
+		{name: "string", a: "a", b: "a"},
+		{name: "zero", a: 0.0, b: math.Copysign(0, -1)},
+		{name: "struct", a: pair{1, "a"}, b: pair{1, "a"}},
+		{name: "array", a: [2]int{1, 2}, b: [2]int{1, 2}},
+		{name: "pointer", a: &x, b: &x},
+		{name: "nil", a: nil, b: nil},
+		{name: "interface", a: [1]interface{}{"a"}, b: [1]interface{}{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.a != tt.b {
+				t.Fatalf("%v != %v", tt.a, tt.b)
+			}
+			if hashOf(tt.a) != hashOf(tt.b) {
+				t.Errorf("hashOf(%v) != hashOf(%v)", tt.a, tt.b)
+			}
+		})
+	}
+	if hashOf("ab") == hashOf("ba") || hashOf(pair{1, "a"}) == hashOf(pair{2, "a"}) {
+		t.Errorf("hashOf() collides on distinct values")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("hashOf([]int) did not panic")
+		}
+	}()
+	hashOf([]int{1})
+}
+
+func TestISet_Hash(t *testing.T) {
+	want := NewThreadUnsafeSet(1, "a", 2.5).Hash()
+	cow := NewCOWSet(2.5, "a", 1)
+	tests := []struct {
+		name string
+		s    ISet
+	}{
+		{name: "NewSet", s: NewSet("a", 2.5, 1)},
+		{name: "NewShardedSet", s: NewShardedSet(4, 2.5, 1, "a")},
+		{name: "NewLockFreeSet", s: NewLockFreeSet(1, 2.5, "a")},
+		{name: "NewCOWSet", s: cow},
+		{name: "Snapshot", s: cow.Snapshot()},
+		{name: "NewFrozen", s: NewFrozen(2.5, "a", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.Hash(); got != want {
+				t.Errorf("Hash() = %v, want %v", got, want)
+			}
+		})
+	}
+	if NewSet(1, 2).Hash() == NewSet(1, 3).Hash() || NewSet().Hash() == NewSet(0).Hash() {
+		t.Errorf("Hash() collides on distinct sets")
+	}
+}
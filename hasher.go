@@ -0,0 +1,342 @@
+package set
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Hasher hashes and compares the elements of a set built by NewHashSet.
+// Elements that are Equal must have the same Hash.
+type Hasher interface {
+	Hash(elem interface{}) uint64
+	Equal(a, b interface{}) bool
+}
+
+// NewHasher returns a Hasher made of a hash and an equal function.
+// Examples:
+// hash := func(elem interface{}) uint64 { return uint64(elem.(*User).ID) }
+// equal := func(a, b interface{}) bool { return a.(*User).ID == b.(*User).ID }
+// byID := NewHasher(hash, equal)
+func NewHasher(hash func(elem interface{}) uint64, equal func(a, b interface{}) bool) Hasher {
+	return funcHasher{hash: hash, equal: equal}
+}
+
+type funcHasher struct {
+	hash  func(elem interface{}) uint64
+	equal func(a, b interface{}) bool
+	name  string // Go syntax of a package level Hasher, for %#v
+}
+
+func (h funcHasher) Hash(elem interface{}) uint64 {
+	return h.hash(elem)
+}
+
+func (h funcHasher) Equal(a, b interface{}) bool {
+	return h.equal(a, b)
+}
+
+// DeepHasher compares elements with reflect.DeepEqual, so slices, maps and structs containing them
+// can be elements: two elements are the same if their contents are.
+var DeepHasher Hasher = funcHasher{hash: deepHashOf, equal: reflect.DeepEqual, name: "set.DeepHasher"}
+
+// deepHashMaxDepth bounds the walk of deepHashOf, so cyclic values hash in finite time.
+const deepHashMaxDepth = 16
+
+func deepHashOf(v interface{}) uint64 {
+	h := newFnv64()
+	deepHashValue(&h, reflect.ValueOf(v), 0)
+	return h.sum()
+}
+
+// deepHashValue hashes v consistently with reflect.DeepEqual: pointers are followed, maps hash
+// independently of their iteration order, and functions only hash whether they are nil.
+func deepHashValue(h *fnv64, v reflect.Value, depth int) {
+	if !v.IsValid() {
+		h.writeByte(0)
+		return
+	}
+	h.writeByte(byte(v.Kind()))
+	if depth > deepHashMaxDepth {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			h.writeByte(1)
+		} else {
+			h.writeByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.writeUint64(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		h.writeUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		h.writeFloat64(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		h.writeFloat64(real(v.Complex()))
+		h.writeFloat64(imag(v.Complex()))
+	case reflect.String:
+		h.writeString(v.String())
+	case reflect.Array, reflect.Slice:
+		h.writeUint64(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			deepHashValue(h, v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		var sum uint64
+		iter := v.MapRange()
+		for iter.Next() {
+			e := newFnv64()
+			deepHashValue(&e, iter.Key(), depth+1)
+			deepHashValue(&e, iter.Value(), depth+1)
+			sum += e.sum()
+		}
+		h.writeUint64(uint64(v.Len()))
+		h.writeUint64(sum)
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			deepHashValue(h, v.Elem(), depth+1)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			deepHashValue(h, v.Field(i), depth+1)
+		}
+	case reflect.Chan, reflect.UnsafePointer:
+		h.writeUint64(uint64(v.Pointer()))
+	case reflect.Func:
+		if v.IsNil() {
+			h.writeByte(0)
+		}
+	}
+}
+
+// NewHashSet returns a thread safe set whose elements are hashed and compared by h instead of ==,
+// so they do not need to be comparable, see DeepHasher. Elements with the same hash are told apart with h.Equal.
+// The algebra returns sets using the same Hasher. Combining it with sets of other implementations uses their
+// Contains method, which requires the elements to be comparable.
+// Examples:
+// s := NewHashSet(DeepHasher, []int{1, 2})
+// s.Contains([]int{1, 2}) // true
+func NewHashSet(h Hasher, elems ...interface{}) ISet {
+	s := &hashSet{hasher: h, buckets: make(map[uint64][]interface{}, len(elems))}
+	s.Adds(elems...)
+	return s
+}
+
+type hashSet struct {
+	rwm     sync.RWMutex
+	hasher  Hasher
+	buckets map[uint64][]interface{} // elements by hash, colliding ones share a bucket
+	n       int
+}
+
+// find returns the hash of elem and its index in its bucket, -1 if it is not in the set.
+// The caller must hold the lock.
+func (s *hashSet) find(elem interface{}) (uint64, int) {
+	h := s.hasher.Hash(elem)
+	for i, x := range s.buckets[h] {
+		if s.hasher.Equal(x, elem) {
+			return h, i
+		}
+	}
+	return h, -1
+}
+
+func (s *hashSet) Empty() bool {
+	return s.Cardinality() == 0
+}
+
+func (s *hashSet) Singleton() bool {
+	return s.Cardinality() == 1
+}
+
+func (s *hashSet) Cardinality() int {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	return s.n
+}
+
+func (s *hashSet) ToSlice() ISlice {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	result := make(Slice, 0, s.n)
+	for _, bucket := range s.buckets {
+		result = append(result, bucket...)
+	}
+	return result
+}
+
+// checkElems accepts any element, the Hasher does not need them to be comparable.
+func (s *hashSet) checkElems(elems []interface{}, adding bool) error {
+	return nil
+}
+
+func (s *hashSet) Adds(elems ...interface{}) bool {
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	var exist bool
+	for i := 0; i < len(elems); i++ {
+		if h, j := s.find(elems[i]); j >= 0 {
+			exist = true
+		} else {
+			s.buckets[h] = append(s.buckets[h], elems[i])
+			s.n++
+		}
+	}
+	return !exist
+}
+
+func (s *hashSet) Removes(elems ...interface{}) bool {
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	var notExist bool
+	for i := 0; i < len(elems); i++ {
+		h, j := s.find(elems[i])
+		if j < 0 {
+			notExist = true
+			continue
+		}
+		s.removeAt(h, j)
+	}
+	return !notExist
+}
+
+func (s *hashSet) removeAt(h uint64, i int) {
+	bucket := s.buckets[h]
+	if len(bucket) == 1 {
+		delete(s.buckets, h)
+	} else {
+		bucket[i] = bucket[len(bucket)-1]
+		bucket[len(bucket)-1] = nil
+		s.buckets[h] = bucket[:len(bucket)-1]
+	}
+	s.n--
+}
+
+func (s *hashSet) IsSub(other ISet) bool {
+	if s.Cardinality() > other.Cardinality() {
+		return false
+	}
+	return other.Contains(s.ToSlice().Interface()...)
+}
+
+func (s *hashSet) Unions(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Adds(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *hashSet) Intersections(others ...ISet) ISet {
+	result := NewHashSet(s.hasher)
+	var baseSet ISet = s
+	var diffSets []ISet
+	for _, other := range others {
+		if other.Cardinality() < baseSet.Cardinality() {
+			diffSets = append(diffSets, baseSet)
+			baseSet = other
+		} else {
+			diffSets = append(diffSets, other)
+		}
+	}
+Loop:
+	for _, elem := range baseSet.ToSlice().Interface() {
+		for _, diffSet := range diffSets {
+			if !diffSet.Contains(elem) {
+				continue Loop
+			}
+		}
+		result.Adds(elem)
+	}
+	return result
+}
+
+func (s *hashSet) Complements(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Removes(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *hashSet) Clear() {
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	s.buckets, s.n = make(map[uint64][]interface{}), 0
+}
+
+func (s *hashSet) Contains(elems ...interface{}) bool {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	for i := 0; i < len(elems); i++ {
+		if _, j := s.find(elems[i]); j < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *hashSet) Clone() ISet {
+	return NewHashSet(s.hasher, s.ToSlice().Interface()...)
+}
+
+func (s *hashSet) Equal(other ISet) bool {
+	if other.Cardinality() != s.Cardinality() {
+		return false
+	}
+	return s.Contains(other.ToSlice().Interface()...)
+}
+
+func (s *hashSet) Pop() interface{} {
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	for h, bucket := range s.buckets {
+		elem := bucket[len(bucket)-1]
+		s.removeAt(h, len(bucket)-1)
+		return elem
+	}
+	return nil
+}
+
+func (s *hashSet) String() string {
+	return formatString(s)
+}
+
+// Format prints %#v as a NewHashSet call. The functions of a Hasher made by NewHasher cannot be printed,
+// they are named hash and equal.
+func (s *hashSet) Format(f fmt.State, verb rune) {
+	if verb != 'v' || !f.Flag('#') {
+		formatSet(f, verb, "NewSet", s)
+		return
+	}
+	args := "set.NewHasher(hash, equal)"
+	if h, ok := s.hasher.(funcHasher); !ok {
+		args = fmt.Sprintf("%#v", s.hasher)
+	} else if h.name != "" {
+		args = h.name
+	}
+	if elems := goSyntaxElems(s.ToSlice().Interface()); elems != "" {
+		args += ", " + elems
+	}
+	fmt.Fprintf(f, "set.NewHashSet(%s)", args)
+}
+
+// Hash hashes comparable elements like the other sets do, so it matches their hashes when the Hasher
+// agrees with ==. Other elements, such as slices, contribute their Hasher hash.
+func (s *hashSet) Hash() uint64 {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	var h setHash
+	for x, bucket := range s.buckets {
+		for _, elem := range bucket {
+			if CheckHashable(elem) == nil {
+				h.add(elem)
+			} else {
+				h.addHash(x)
+			}
+		}
+	}
+	return h.sum64()
+}
@@ -0,0 +1,124 @@
+package set
+
+import (
+	"fmt"
+	"testing"
+)
+
+type testUser struct {
+	ID   int
+	Tags []string
+}
+
+func TestNewHashSet(t *testing.T) {
+	s := NewHashSet(DeepHasher, []int{1, 2}, []int{3}, map[string]int{"a": 1, "b": 2})
+	tests := []struct {
+		name string
+		elem interface{}
+		want bool
+	}{
+		{name: "slice", elem: []int{1, 2}, want: true},
+		{name: "other slice", elem: []int{2, 1}, want: false},
+		{name: "map", elem: map[string]int{"b": 2, "a": 1}, want: true},
+		{name: "other map", elem: map[string]int{"a": 1}, want: false},
+		{name: "struct with slice", elem: testUser{ID: 1}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Contains(tt.elem); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if s.Adds([]int{1, 2}) || s.Cardinality() != 3 || !s.Adds(testUser{ID: 1, Tags: []string{"x"}}) {
+		t.Errorf("Adds() = %v, want deep equal elements deduplicated", s)
+	}
+	if !s.Removes([]int{3}) || s.Removes([]int{3}) || s.Cardinality() != 3 {
+		t.Errorf("Removes() = %v", s)
+	}
+	other := NewHashSet(DeepHasher, []int{1, 2}, "x")
+	if got, want := s.Intersections(other), NewHashSet(DeepHasher, []int{1, 2}); !got.Equal(want) {
+		t.Errorf("Intersections() = %v, want %v", got, want)
+	}
+	if got := s.Unions(other); got.Cardinality() != 4 || !got.Contains("x", []int{1, 2}) {
+		t.Errorf("Unions() = %v", got)
+	}
+	if got := s.Complements(other); got.Cardinality() != 2 || got.Contains([]int{1, 2}) {
+		t.Errorf("Complements() = %v", got)
+	}
+	if !NewHashSet(DeepHasher, []int{1, 2}).IsSub(s) || s.Clone().Hash() != s.Hash() || !s.Clone().Equal(s) {
+		t.Errorf("IsSub(), Clone() of %v", s)
+	}
+	if a, b := NewHashSet(DeepHasher, 1, "x", [2]int{1, 2}), NewSet(1, "x", [2]int{1, 2}); !a.Equal(b) || a.Hash() != b.Hash() {
+		t.Errorf("Hash() = %v, want %v, the hash of the equal %v", a.Hash(), b.Hash(), b)
+	}
+	for !s.Empty() {
+		s.Pop()
+	}
+	if s.Pop() != nil || s.String() != "{}" {
+		t.Errorf("Pop() = %v, want an empty set", s)
+	}
+}
+
+func TestNewHashSet_Collisions(t *testing.T) {
+	byID := NewHasher(
+		func(elem interface{}) uint64 { return uint64(elem.(*testUser).ID % 2) }, // collides a lot on purpose
+		func(a, b interface{}) bool { return a.(*testUser).ID == b.(*testUser).ID },
+	)
+	s := NewHashSet(byID)
+	for i := 0; i < 100; i++ {
+		s.Adds(&testUser{ID: i})
+	}
+	if s.Adds(&testUser{ID: 42, Tags: []string{"other"}}) || s.Cardinality() != 100 {
+		t.Errorf("Adds() = %v elements, want 100", s.Cardinality())
+	}
+	for i := 0; i < 100; i += 3 {
+		s.Removes(&testUser{ID: i})
+	}
+	for i := 0; i < 100; i++ {
+		if got, want := s.Contains(&testUser{ID: i}), i%3 != 0; got != want {
+			t.Errorf("Contains(%v) = %v, want %v", i, got, want)
+		}
+	}
+	s.Clear()
+	if !s.Empty() || s.Singleton() {
+		t.Errorf("Clear() = %v, want an empty set", s)
+	}
+}
+
+func TestHashSet_Format(t *testing.T) {
+	byLen := NewHasher(func(elem interface{}) uint64 { return uint64(len(elem.(string))) }, func(a, b interface{}) bool {
+		return len(a.(string)) == len(b.(string))
+	})
+	tests := []struct {
+		s    ISet
+		want string
+	}{
+		{NewHashSet(DeepHasher, []int{1}, "a"), `set.NewHashSet(set.DeepHasher, "a", []int{1})`},
+		{NewHashSet(DeepHasher), "set.NewHashSet(set.DeepHasher)"},
+		{NewHashSet(byLen, "a"), `set.NewHashSet(set.NewHasher(hash, equal), "a")`},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf("%#v", tt.s); got != tt.want {
+			t.Errorf("Sprintf(%%#v) = %v, want %v", got, tt.want)
+		}
+	}
+	if got, want := fmt.Sprintf("%v", NewHashSet(DeepHasher, 2, 1)), "{1,2}"; got != want {
+		t.Errorf("Sprintf(%%v) = %v, want %v", got, want)
+	}
+}
+
+func Test_deepHashOf(t *testing.T) {
+	type node struct {
+		Next *node
+	}
+	cyclic := &node{}
+	cyclic.Next = cyclic
+	deepHashOf(cyclic) // must terminate
+	if deepHashOf([]int{1, 2}) != deepHashOf([]int{1, 2}) || deepHashOf([]int{1, 2}) == deepHashOf([]int{2, 1}) {
+		t.Errorf("deepHashOf() inconsistent on slices")
+	}
+	if deepHashOf(&testUser{ID: 1}) != deepHashOf(&testUser{ID: 1}) {
+		t.Errorf("deepHashOf() should follow pointers")
+	}
+}
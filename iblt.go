@@ -0,0 +1,301 @@
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrIBLTDecode is returned when an IBLT holds a larger difference than it can decode.
+	ErrIBLTDecode = errors.New("go-set: IBLT Decode() err, the difference does not fit in the sketch")
+	// ErrIBLTMismatch is returned when subtracting IBLTs of different sizes.
+	ErrIBLTMismatch = errors.New("go-set: IBLT Subtract() err, sketches differ in size")
+)
+
+const (
+	ibltHashes      = 3 // cells each element is stored in
+	ibltMinCells    = 4 * ibltHashes
+	ibltMaxAttempts = 8
+)
+
+// ibltCell sums the elements stored in it: their number, and the XOR of their encodings,
+// of the lengths of their encodings and of their hashes.
+type ibltCell struct {
+	count   int64
+	lenSum  uint64
+	hashSum uint64
+	keySum  []byte
+}
+
+func (c *ibltCell) toggle(enc []byte, h uint64, count int64) {
+	c.count += count
+	c.lenSum ^= uint64(len(enc))
+	c.hashSum ^= h
+	if len(c.keySum) < len(enc) {
+		c.keySum = append(c.keySum, make([]byte, len(enc)-len(c.keySum))...)
+	}
+	for i := range enc {
+		c.keySum[i] ^= enc[i]
+	}
+}
+
+// pure returns the encoding held by a cell that stores a single element, or false.
+func (c *ibltCell) pure() ([]byte, bool) {
+	if c.count != 1 && c.count != -1 || c.lenSum > uint64(len(c.keySum)) {
+		return nil, false
+	}
+	enc := c.keySum[:c.lenSum]
+	if hashBytes(enc) != c.hashSum || !zeroBytes(c.keySum[c.lenSum:]) {
+		return nil, false
+	}
+	return enc, true
+}
+
+func (c *ibltCell) empty() bool {
+	return c.count == 0 && c.lenSum == 0 && c.hashSum == 0 && zeroBytes(c.keySum)
+}
+
+func zeroBytes(b []byte) bool {
+	for _, x := range b {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func hashBytes(b []byte) uint64 {
+	h := newFnv64()
+	for _, x := range b {
+		h.writeByte(x)
+	}
+	return h.sum()
+}
+
+// IBLTCells returns the number of cells of an IBLT that decodes a difference of expectedDiff elements
+// with high probability.
+func IBLTCells(expectedDiff int) int {
+	cells := expectedDiff * 2
+	if cells < ibltMinCells {
+		cells = ibltMinCells
+	}
+	return (cells + ibltHashes - 1) / ibltHashes * ibltHashes
+}
+
+// NewIBLT returns an empty invertible Bloom lookup table sized for a difference of expectedDiff elements,
+// see IBLTCells. Two peers reconciling their sets must build their sketches with the same expectedDiff.
+func NewIBLT(expectedDiff int) *IBLT {
+	return &IBLT{cells: make([]ibltCell, IBLTCells(expectedDiff))}
+}
+
+// NewSketch returns an IBLT holding the elements of s, see WriteSet for the supported element types.
+func NewSketch(s ISet, expectedDiff int) (*IBLT, error) {
+	t := NewIBLT(expectedDiff)
+	for _, elem := range s.ToSlice().Interface() {
+		if err := t.Insert(elem); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// IBLT is an invertible Bloom lookup table: a sketch of a set whose size depends on the expected
+// difference with another set rather than on the size of the set. Subtracting the sketch of
+// a peer from the local one and decoding the result yields the symmetric difference of the two sets,
+// as long as it is not much larger than expected. It is not thread safe.
+//
+// Examples:
+//
+//	local, _ := NewSketch(a, 10)
+//	remote, _ := NewSketch(b, 10) // built by the peer and shipped with MarshalBinary
+//	diff, _ := local.Subtract(remote)
+//	onlyA, onlyB, err := diff.Decode()
+type IBLT struct {
+	cells []ibltCell
+}
+
+// Cells returns the number of cells of the table.
+func (t *IBLT) Cells() int {
+	return len(t.cells)
+}
+
+// Insert adds an element to the table.
+func (t *IBLT) Insert(elem interface{}) error {
+	enc, err := appendElem(nil, elem)
+	if err != nil {
+		return err
+	}
+	t.toggle(enc, 1)
+	return nil
+}
+
+func (t *IBLT) toggle(enc []byte, count int64) {
+	h := hashBytes(enc)
+	m := uint64(len(t.cells) / ibltHashes)
+	for i := uint64(0); i < ibltHashes; i++ {
+		// Each hash indexes its own slice of the table, so an element never lands twice in the same cell.
+		x := fnv64(h + i*0x9e3779b97f4a7c15).sum()
+		t.cells[i*m+x%m].toggle(enc, h, count)
+	}
+}
+
+// Subtract returns a new table holding the elements of t that are not in other, with a positive count,
+// and the elements of other that are not in t, with a negative count.
+func (t *IBLT) Subtract(other *IBLT) (*IBLT, error) {
+	if len(t.cells) != len(other.cells) {
+		return nil, ErrIBLTMismatch
+	}
+	result := t.clone()
+	for i := range other.cells {
+		c := &other.cells[i]
+		r := &result.cells[i]
+		r.count -= c.count
+		r.lenSum ^= c.lenSum
+		r.hashSum ^= c.hashSum
+		if len(r.keySum) < len(c.keySum) {
+			r.keySum = append(r.keySum, make([]byte, len(c.keySum)-len(r.keySum))...)
+		}
+		for j := range c.keySum {
+			r.keySum[j] ^= c.keySum[j]
+		}
+	}
+	return result, nil
+}
+
+func (t *IBLT) clone() *IBLT {
+	result := &IBLT{cells: make([]ibltCell, len(t.cells))}
+	for i, c := range t.cells {
+		c.keySum = append([]byte(nil), c.keySum...)
+		result.cells[i] = c
+	}
+	return result
+}
+
+// Decode lists the elements of a table produced by Subtract: positive ones were only in the minuend,
+// negative ones only in the subtrahend. It returns ErrIBLTDecode if the table holds too many elements
+// to be listed, a larger table may succeed. t is left unchanged.
+func (t *IBLT) Decode() (positive, negative ISet, err error) {
+	w := t.clone()
+	positive, negative = NewThreadUnsafeSet(), NewThreadUnsafeSet()
+	for progress := true; progress; {
+		progress = false
+		for i := range w.cells {
+			enc, ok := w.cells[i].pure()
+			if !ok {
+				continue
+			}
+			elem, _, err := decodeElem(enc)
+			if err != nil {
+				return nil, nil, ErrIBLTDecode
+			}
+			side := positive
+			if w.cells[i].count < 0 {
+				side = negative
+			}
+			if !side.Adds(elem) {
+				return nil, nil, ErrIBLTDecode
+			}
+			w.toggle(append([]byte(nil), enc...), -w.cells[i].count)
+			progress = true
+		}
+	}
+	for i := range w.cells {
+		if !w.cells[i].empty() {
+			return nil, nil, ErrIBLTDecode
+		}
+	}
+	return positive, negative, nil
+}
+
+// MarshalBinary encodes the table, to ship it to a peer.
+func (t *IBLT) MarshalBinary() ([]byte, error) {
+	b := appendUvarint(nil, uint64(len(t.cells)))
+	for _, c := range t.cells {
+		b = appendVarint(b, c.count)
+		b = appendUvarint(b, c.lenSum)
+		b = appendUint64(b, c.hashSum)
+		b = appendUvarint(b, uint64(len(c.keySum)))
+		b = append(b, c.keySum...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary replaces the table with one encoded by MarshalBinary.
+func (t *IBLT) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return ibltDecodeErr(err)
+	}
+	if n%ibltHashes != 0 || n > uint64(r.Len()) {
+		return ibltDecodeErr(fmt.Errorf("invalid number of cells %d", n))
+	}
+	cells := make([]ibltCell, n)
+	for i := range cells {
+		c := &cells[i]
+		if c.count, err = binary.ReadVarint(r); err != nil {
+			return ibltDecodeErr(err)
+		}
+		if c.lenSum, err = binary.ReadUvarint(r); err != nil {
+			return ibltDecodeErr(err)
+		}
+		var h [8]byte
+		if _, err = io.ReadFull(r, h[:]); err != nil {
+			return ibltDecodeErr(err)
+		}
+		c.hashSum = binary.LittleEndian.Uint64(h[:])
+		l, err := binary.ReadUvarint(r)
+		if err != nil {
+			return ibltDecodeErr(err)
+		}
+		if l > uint64(r.Len()) {
+			return ibltDecodeErr(io.ErrUnexpectedEOF)
+		}
+		c.keySum = make([]byte, l)
+		io.ReadFull(r, c.keySum)
+	}
+	if r.Len() != 0 {
+		return ibltDecodeErr(errors.New("trailing bytes"))
+	}
+	t.cells = cells
+	return nil
+}
+
+func ibltDecodeErr(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("go-set: IBLT UnmarshalBinary() err, %v", err)
+}
+
+// Reconcile computes the symmetric difference between local and the set of a peer, without shipping
+// either set: remote must return the sketch of the peer set for the given expected difference,
+// typically by asking the peer for it over the network. If the difference does not fit,
+// Reconcile retries with a sketch twice as large, up to 8 times.
+// It returns the elements only local has, and the elements only the peer has.
+func Reconcile(local ISet, expectedDiff int, remote func(expectedDiff int) (*IBLT, error)) (onlyLocal, onlyRemote ISet, err error) {
+	if expectedDiff < 1 {
+		expectedDiff = 1
+	}
+	for i := 0; i < ibltMaxAttempts; i, expectedDiff = i+1, expectedDiff*2 {
+		theirs, err := remote(expectedDiff)
+		if err != nil {
+			return nil, nil, err
+		}
+		ours, err := NewSketch(local, expectedDiff)
+		if err != nil {
+			return nil, nil, err
+		}
+		diff, err := ours.Subtract(theirs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if onlyLocal, onlyRemote, err = diff.Decode(); err != ErrIBLTDecode {
+			return onlyLocal, onlyRemote, err
+		}
+	}
+	return nil, nil, ErrIBLTDecode
+}
@@ -0,0 +1,95 @@
+package set
+
+import (
+	"fmt"
+	"testing"
+)
+
+// mostlyIdentical returns two sets sharing n elements, plus onlyA and onlyB elements of their own.
+func mostlyIdentical(n, onlyA, onlyB int) (a, b, wantA, wantB ISet) {
+	a, b, wantA, wantB = NewSet(), NewSet(), NewSet(), NewSet()
+	for i := 0; i < n; i++ {
+		a.Adds(i)
+		b.Adds(i)
+	}
+	for i := 0; i < onlyA; i++ {
+		a.Adds(fmt.Sprintf("a-%d", i))
+		wantA.Adds(fmt.Sprintf("a-%d", i))
+	}
+	for i := 0; i < onlyB; i++ {
+		b.Adds(-i - 1)
+		wantB.Adds(-i - 1)
+	}
+	return a, b, wantA, wantB
+}
+
+func TestIBLT_Decode(t *testing.T) {
+	tests := []struct {
+		name         string
+		onlyA, onlyB int
+	}{
+		{name: "identical"},
+		{name: "one side", onlyA: 20},
+		{name: "both sides", onlyA: 15, onlyB: 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b, wantA, wantB := mostlyIdentical(10000, tt.onlyA, tt.onlyB)
+			sa, err := NewSketch(a, 50)
+			if err != nil {
+				t.Fatalf("NewSketch() error = %v", err)
+			}
+			sb, _ := NewSketch(b, 50)
+			diff, err := sa.Subtract(sb)
+			if err != nil {
+				t.Fatalf("Subtract() error = %v", err)
+			}
+			gotA, gotB, err := diff.Decode()
+			if err != nil || !gotA.Equal(wantA) || !gotB.Equal(wantB) {
+				t.Errorf("Decode() = %v, %v, %v, want %v, %v", gotA, gotB, err, wantA, wantB)
+			}
+		})
+	}
+}
+
+func TestIBLT_DecodeOverflow(t *testing.T) {
+	a, b, _, _ := mostlyIdentical(100, 200, 200)
+	sa, _ := NewSketch(a, 10)
+	sb, _ := NewSketch(b, 10)
+	diff, _ := sa.Subtract(sb)
+	if _, _, err := diff.Decode(); err != ErrIBLTDecode {
+		t.Errorf("Decode() error = %v, want %v", err, ErrIBLTDecode)
+	}
+	if _, err := sa.Subtract(NewIBLT(1000)); err != ErrIBLTMismatch {
+		t.Errorf("Subtract() error = %v, want %v", err, ErrIBLTMismatch)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	a, b, wantA, wantB := mostlyIdentical(10000, 100, 150)
+	var sizes []int
+	// The peer is simulated in-process: its sketch travels through MarshalBinary like it would on the wire.
+	remote := func(expectedDiff int) (*IBLT, error) {
+		sizes = append(sizes, expectedDiff)
+		sketch, err := NewSketch(b, expectedDiff)
+		if err != nil {
+			return nil, err
+		}
+		data, err := sketch.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		got := &IBLT{}
+		return got, got.UnmarshalBinary(data)
+	}
+	gotA, gotB, err := Reconcile(a, 10, remote)
+	if err != nil || !gotA.Equal(wantA) || !gotB.Equal(wantB) {
+		t.Fatalf("Reconcile() = %v, %v, %v, want %v, %v", gotA.Cardinality(), gotB.Cardinality(), err, wantA.Cardinality(), wantB.Cardinality())
+	}
+	if len(sizes) < 2 || sizes[1] != 2*sizes[0] {
+		t.Errorf("Reconcile() asked for sketches of %v, want growing retries", sizes)
+	}
+	if err := (&IBLT{}).UnmarshalBinary([]byte{3, 0}); err == nil {
+		t.Errorf("UnmarshalBinary() error = %v, want an error", err)
+	}
+}
@@ -0,0 +1,297 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrCheckpointExpired is returned by UndoTo when the checkpoint fell out of the bounded history.
+var ErrCheckpointExpired = errors.New("go-set: UndoTo() err, checkpoint has expired")
+
+// ErrCheckpointInvalid is returned by UndoTo when the checkpoint was undone and then overwritten by new mutations.
+var ErrCheckpointInvalid = errors.New("go-set: UndoTo() err, checkpoint is no longer in the history")
+
+// ErrCheckpointUndone is returned by UndoTo when the checkpoint was undone and can still be redone,
+// so UndoTo cannot reach it. Redo until Checkpoint returns it instead.
+var ErrCheckpointUndone = errors.New("go-set: UndoTo() err, checkpoint was undone, redo to reach it")
+
+// Checkpoint identifies a position in the history of a JournaledSet, see JournaledSet.Checkpoint.
+type Checkpoint uint64
+
+// NewJournaledSet wraps s so that its mutations can be undone and redone.
+// limit bounds the number of mutations that can be undone, the oldest ones are forgotten first;
+// a limit below 1 keeps the whole history.
+// s must only be mutated through the returned JournaledSet from then on.
+func NewJournaledSet(s ISet, limit int) *JournaledSet {
+	return &JournaledSet{s: s, limit: limit}
+}
+
+// JournaledSet is a thread safe ISet with multi-level undo and redo.
+// Each call to Adds, Removes, Clear or Pop that changes the set is journaled as one step,
+// together with the exact elements it added or removed, so undoing a Clear or a Pop restores
+// what was there. Making a new change after undoing discards the steps that could have been redone.
+type JournaledSet struct {
+	rwm   sync.RWMutex
+	s     ISet
+	undo  []journalEntry
+	redo  []journalEntry
+	limit int
+	seq   Checkpoint // sequence number of the latest journaled step
+	floor Checkpoint // sequence number of the latest step forgotten because of limit
+}
+
+type journalEntry struct {
+	seq     Checkpoint
+	added   []interface{}
+	removed []interface{}
+}
+
+// apply replays e on s, or reverts it when inverse is true. The caller must hold the write lock.
+func (j *JournaledSet) apply(e journalEntry, inverse bool) {
+	added, removed := e.added, e.removed
+	if inverse {
+		added, removed = removed, added
+	}
+	j.s.Removes(removed...)
+	j.s.Adds(added...)
+}
+
+// journal records a step. The caller must hold the write lock.
+func (j *JournaledSet) journal(added, removed []interface{}) {
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	j.seq++
+	j.undo = append(j.undo, journalEntry{seq: j.seq, added: added, removed: removed})
+	j.redo = nil
+	if j.limit > 0 && len(j.undo) > j.limit {
+		j.floor = j.undo[0].seq
+		j.undo = append([]journalEntry(nil), j.undo[1:]...)
+	}
+}
+
+// Undo reverts the latest step. Returns whether there was a step to undo.
+// Examples:
+// j := NewJournaledSet(NewSet(1, 2), 0)
+// j.Clear()  // j={}
+// j.Undo()   // j={1,2} return true
+// j.Undo()   // j={1,2} return false
+func (j *JournaledSet) Undo() bool {
+	j.rwm.Lock()
+	defer j.rwm.Unlock()
+	return j.undoLocked()
+}
+
+func (j *JournaledSet) undoLocked() bool {
+	if len(j.undo) == 0 {
+		return false
+	}
+	e := j.undo[len(j.undo)-1]
+	j.undo = j.undo[:len(j.undo)-1]
+	j.apply(e, true)
+	j.redo = append(j.redo, e)
+	return true
+}
+
+// Redo replays the latest undone step. Returns whether there was a step to redo.
+func (j *JournaledSet) Redo() bool {
+	j.rwm.Lock()
+	defer j.rwm.Unlock()
+	if len(j.redo) == 0 {
+		return false
+	}
+	e := j.redo[len(j.redo)-1]
+	j.redo = j.redo[:len(j.redo)-1]
+	j.apply(e, false)
+	j.undo = append(j.undo, e)
+	return true
+}
+
+// CanUndo returns how many steps can be undone.
+func (j *JournaledSet) CanUndo() int {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return len(j.undo)
+}
+
+// CanRedo returns how many steps can be redone.
+func (j *JournaledSet) CanRedo() int {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return len(j.redo)
+}
+
+// Checkpoint returns the current position in the history, to be passed to UndoTo later.
+func (j *JournaledSet) Checkpoint() Checkpoint {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	if len(j.undo) == 0 {
+		return j.floor
+	}
+	return j.undo[len(j.undo)-1].seq
+}
+
+// UndoTo undoes every step made after the checkpoint, each of them can be redone afterwards.
+// Examples:
+// j := NewJournaledSet(NewSet(1), 0)
+// cp := j.Checkpoint()
+// j.Adds(2)
+// j.Removes(1)
+// j.UndoTo(cp) // j={1} return nil
+func (j *JournaledSet) UndoTo(cp Checkpoint) error {
+	j.rwm.Lock()
+	defer j.rwm.Unlock()
+	if cp < j.floor {
+		return ErrCheckpointExpired
+	}
+	if cp != j.floor {
+		var found bool
+		for _, e := range j.undo {
+			if e.seq == cp {
+				found = true
+				break
+			}
+		}
+		if !found {
+			for _, e := range j.redo {
+				if e.seq == cp {
+					return ErrCheckpointUndone
+				}
+			}
+			return ErrCheckpointInvalid
+		}
+	}
+	for len(j.undo) > 0 && j.undo[len(j.undo)-1].seq > cp {
+		j.undoLocked()
+	}
+	return nil
+}
+
+func (j *JournaledSet) Empty() bool {
+	return j.Cardinality() == 0
+}
+
+func (j *JournaledSet) Singleton() bool {
+	return j.Cardinality() == 1
+}
+
+func (j *JournaledSet) Cardinality() int {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.Cardinality()
+}
+
+func (j *JournaledSet) ToSlice() ISlice {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.ToSlice()
+}
+
+func (j *JournaledSet) checkElems(elems []interface{}, adding bool) error {
+	return checkElems(j.s, elems, adding)
+}
+
+func (j *JournaledSet) Adds(elems ...interface{}) bool {
+	j.rwm.Lock()
+	defer j.rwm.Unlock()
+	var added []interface{}
+	for i := 0; i < len(elems); i++ {
+		if j.s.Adds(elems[i]) {
+			added = append(added, elems[i])
+		}
+	}
+	j.journal(added, nil)
+	return len(added) == len(elems)
+}
+
+func (j *JournaledSet) Removes(elems ...interface{}) bool {
+	j.rwm.Lock()
+	defer j.rwm.Unlock()
+	var removed []interface{}
+	for i := 0; i < len(elems); i++ {
+		if j.s.Removes(elems[i]) {
+			removed = append(removed, elems[i])
+		}
+	}
+	j.journal(nil, removed)
+	return len(removed) == len(elems)
+}
+
+func (j *JournaledSet) IsSub(other ISet) bool {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.IsSub(other)
+}
+
+func (j *JournaledSet) Unions(others ...ISet) ISet {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.Unions(others...)
+}
+
+func (j *JournaledSet) Intersections(others ...ISet) ISet {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.Intersections(others...)
+}
+
+func (j *JournaledSet) Complements(others ...ISet) ISet {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.Complements(others...)
+}
+
+func (j *JournaledSet) Clear() {
+	j.rwm.Lock()
+	defer j.rwm.Unlock()
+	removed := j.s.ToSlice().Interface()
+	j.s.Clear()
+	j.journal(nil, removed)
+}
+
+func (j *JournaledSet) Contains(elems ...interface{}) bool {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.Contains(elems...)
+}
+
+// Clone returns a clone of the current state using the wrapped implementation, without history.
+func (j *JournaledSet) Clone() ISet {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.Clone()
+}
+
+func (j *JournaledSet) Equal(other ISet) bool {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.Equal(other)
+}
+
+func (j *JournaledSet) Pop() interface{} {
+	j.rwm.Lock()
+	defer j.rwm.Unlock()
+	n := j.s.Cardinality()
+	elem := j.s.Pop()
+	if j.s.Cardinality() < n {
+		j.journal(nil, []interface{}{elem})
+	}
+	return elem
+}
+
+func (j *JournaledSet) String() string {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.String()
+}
+
+func (j *JournaledSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", j)
+}
+
+func (j *JournaledSet) Hash() uint64 {
+	j.rwm.RLock()
+	defer j.rwm.RUnlock()
+	return j.s.Hash()
+}
@@ -0,0 +1,101 @@
+package set
+
+import "testing"
+
+func TestJournaledSet_UndoRedo(t *testing.T) {
+	j := NewJournaledSet(NewSet(1, 2), 0)
+	j.Adds(3, 4)
+	j.Adds(4) // no change, not journaled
+	j.Removes(1, 9)
+	popped := j.Pop()
+	j.Clear()
+	states := []ISet{
+		NewSet(2, 3, 4).Complements(NewSet(popped)),
+		NewSet(2, 3, 4),
+		NewSet(1, 2, 3, 4),
+		NewSet(1, 2),
+	}
+	if j.CanUndo() != len(states) {
+		t.Fatalf("CanUndo() = %v, want %v", j.CanUndo(), len(states))
+	}
+	for i, want := range states {
+		if !j.Undo() || !j.Equal(want) {
+			t.Errorf("Undo() #%d = %v, want %v", i, j, want)
+		}
+	}
+	if j.Undo() {
+		t.Errorf("Undo() = %v, want %v", true, false)
+	}
+	for i := len(states) - 2; i >= 0; i-- {
+		if want := states[i]; !j.Redo() || !j.Equal(want) {
+			t.Errorf("Redo() = %v, want %v", j, want)
+		}
+	}
+	if !j.Redo() || !j.Empty() {
+		t.Errorf("Redo() = %v, want %v", j, "{}")
+	}
+	if j.Redo() {
+		t.Errorf("Redo() = %v, want %v", true, false)
+	}
+}
+
+func TestJournaledSet_NewChangeDiscardsRedo(t *testing.T) {
+	j := NewJournaledSet(NewThreadUnsafeSet(), 0)
+	j.Adds(1)
+	j.Adds(2)
+	j.Undo()
+	j.Adds(3)
+	if j.CanRedo() != 0 || j.Redo() {
+		t.Errorf("CanRedo() = %v, want %v", j.CanRedo(), 0)
+	}
+	if want := NewSet(1, 3); !j.Equal(want) {
+		t.Errorf("Adds() = %v, want %v", j, want)
+	}
+}
+
+func TestJournaledSet_Limit(t *testing.T) {
+	j := NewJournaledSet(NewThreadUnsafeSet(), 2)
+	cp := j.Checkpoint()
+	j.Adds(1)
+	j.Adds(2)
+	j.Adds(3)
+	if j.CanUndo() != 2 {
+		t.Errorf("CanUndo() = %v, want %v", j.CanUndo(), 2)
+	}
+	if err := j.UndoTo(cp); err != ErrCheckpointExpired {
+		t.Errorf("UndoTo() error = %v, want %v", err, ErrCheckpointExpired)
+	}
+	for j.Undo() {
+	}
+	if want := NewSet(1); !j.Equal(want) {
+		t.Errorf("Undo() = %v, want %v", j, want)
+	}
+}
+
+func TestJournaledSet_UndoTo(t *testing.T) {
+	j := NewJournaledSet(NewSet(1), 0)
+	start := j.Checkpoint()
+	j.Adds(2)
+	middle := j.Checkpoint()
+	j.Removes(1)
+	j.Adds(3)
+	if err := j.UndoTo(middle); err != nil || !j.Equal(NewSet(1, 2)) {
+		t.Errorf("UndoTo() = %v, %v, want %v", j, err, "{1,2}")
+	}
+	if j.CanRedo() != 2 {
+		t.Errorf("CanRedo() = %v, want %v", j.CanRedo(), 2)
+	}
+	if err := j.UndoTo(start); err != nil || !j.Equal(NewSet(1)) {
+		t.Errorf("UndoTo() = %v, %v, want %v", j, err, "{1}")
+	}
+	if err := j.UndoTo(middle); err != ErrCheckpointUndone {
+		t.Errorf("UndoTo() error = %v, want %v", err, ErrCheckpointUndone)
+	}
+	j.Adds(4)
+	if err := j.UndoTo(middle); err != ErrCheckpointInvalid {
+		t.Errorf("UndoTo() error = %v, want %v", err, ErrCheckpointInvalid)
+	}
+	if err := j.UndoTo(start); err != nil || !j.Equal(NewSet(1)) {
+		t.Errorf("UndoTo() = %v, %v, want %v", j, err, "{1}")
+	}
+}
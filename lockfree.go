@@ -0,0 +1,400 @@
+package set
+
+import (
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+const lockFreeInitialBuckets = 16
+
+// NewLockFreeSet returns a lock-free concurrent set.
+// Adds, Removes and Contains never block: every operation is a compare-and-swap retry loop,
+// and whenever a goroutine has to retry it is because another one made progress.
+// The bucket table grows on demand without stopping the world, goroutines that run into
+// an unfinished resize help migrate the buckets they need.
+func NewLockFreeSet(elems ...interface{}) *LockFreeSet {
+	s := &LockFreeSet{head: unsafe.Pointer(newLockFreeTable(lockFreeInitialBuckets, nil))}
+	s.Adds(elems...)
+	return s
+}
+
+// LockFreeSet is a lock-free hash set based on freezable buckets (Liu, Zhang & Spear,
+// "Dynamic-Sized Nonblocking Hash Tables", PODC 2014).
+//
+// Every bucket is an immutable slice of elements published through an atomic pointer,
+// a mutation builds a new bucket and swaps it in with compare-and-swap.
+// To grow, a table twice the size is published in front of the current one; its buckets start
+// uninitialized and are filled lazily by freezing the matching bucket of the previous table
+// and copying the elements that now hash to them.
+//
+// Operations on single elements are linearizable.
+// Operations that visit the whole set (ToSlice, Iter, String, Clear and the set algebra built on them)
+// are weakly consistent: they never fail because of concurrent mutations, every element present
+// for their whole duration is visited exactly once, and elements added or removed meanwhile
+// may or may not be visited. Cardinality is exact when the set is quiescent.
+type LockFreeSet struct {
+	head  unsafe.Pointer // *lockFreeTable
+	count int64
+}
+
+type lockFreeTable struct {
+	buckets []unsafe.Pointer // *lockFreeBucket, nil until initialized
+	mask    uint64
+	pred    unsafe.Pointer // *lockFreeTable the buckets are migrated from, nil once all are initialized
+}
+
+// lockFreeBucket is never modified once published. A frozen bucket belongs to a table that has been
+// superseded, its elements have been, or are being, migrated to the next table.
+type lockFreeBucket struct {
+	elems  []interface{}
+	hashes []uint64
+	frozen bool
+}
+
+var emptyLockFreeBucket = &lockFreeBucket{}
+
+func newLockFreeTable(size int, pred *lockFreeTable) *lockFreeTable {
+	return &lockFreeTable{
+		buckets: make([]unsafe.Pointer, size),
+		mask:    uint64(size - 1),
+		pred:    unsafe.Pointer(pred),
+	}
+}
+
+func (t *lockFreeTable) bucket(i uint64) *lockFreeBucket {
+	return (*lockFreeBucket)(atomic.LoadPointer(&t.buckets[i]))
+}
+
+// initBucket fills bucket i of t from the previous table and returns it.
+func (t *lockFreeTable) initBucket(i uint64) *lockFreeBucket {
+	nb := emptyLockFreeBucket
+	if pred := (*lockFreeTable)(atomic.LoadPointer(&t.pred)); pred != nil {
+		pb := pred.freeze(i & pred.mask)
+		nb = &lockFreeBucket{}
+		for j, h := range pb.hashes {
+			if h&t.mask == i {
+				nb.elems = append(nb.elems, pb.elems[j])
+				nb.hashes = append(nb.hashes, h)
+			}
+		}
+	}
+	atomic.CompareAndSwapPointer(&t.buckets[i], nil, unsafe.Pointer(nb))
+	return t.bucket(i)
+}
+
+// freeze marks bucket i of t as frozen, so it can no longer be mutated, and returns it.
+func (t *lockFreeTable) freeze(i uint64) *lockFreeBucket {
+	for {
+		b := t.bucket(i)
+		if b == nil {
+			b = t.initBucket(i)
+		}
+		if b.frozen {
+			return b
+		}
+		fb := &lockFreeBucket{elems: b.elems, hashes: b.hashes, frozen: true}
+		if atomic.CompareAndSwapPointer(&t.buckets[i], unsafe.Pointer(b), unsafe.Pointer(fb)) {
+			return fb
+		}
+	}
+}
+
+func (b *lockFreeBucket) index(elem interface{}, h uint64) int {
+	for i := range b.hashes {
+		if b.hashes[i] == h && b.elems[i] == elem {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *LockFreeSet) table() *lockFreeTable {
+	return (*lockFreeTable)(atomic.LoadPointer(&s.head))
+}
+
+// bucket returns the live bucket an element with hash h belongs to, along with its table and index.
+func (s *LockFreeSet) bucket(h uint64) (*lockFreeTable, uint64, *lockFreeBucket) {
+	for {
+		t := s.table()
+		i := h & t.mask
+		b := t.bucket(i)
+		if b == nil {
+			b = t.initBucket(i)
+		}
+		if !b.frozen {
+			return t, i, b
+		}
+		// t has been superseded by a larger table, retry against the new head.
+	}
+}
+
+func (s *LockFreeSet) add(elem interface{}) bool {
+	h := hashOf(elem)
+	for {
+		t, i, b := s.bucket(h)
+		if b.index(elem, h) >= 0 {
+			return false
+		}
+		nb := &lockFreeBucket{
+			elems:  append(b.elems[:len(b.elems):len(b.elems)], elem),
+			hashes: append(b.hashes[:len(b.hashes):len(b.hashes)], h),
+		}
+		if atomic.CompareAndSwapPointer(&t.buckets[i], unsafe.Pointer(b), unsafe.Pointer(nb)) {
+			if n := atomic.AddInt64(&s.count, 1); n > int64(len(t.buckets)) {
+				s.grow(t)
+			}
+			return true
+		}
+	}
+}
+
+func (s *LockFreeSet) remove(elem interface{}) bool {
+	h := hashOf(elem)
+	for {
+		t, i, b := s.bucket(h)
+		j := b.index(elem, h)
+		if j < 0 {
+			return false
+		}
+		nb := &lockFreeBucket{
+			elems:  make([]interface{}, 0, len(b.elems)-1),
+			hashes: make([]uint64, 0, len(b.hashes)-1),
+		}
+		nb.elems = append(append(nb.elems, b.elems[:j]...), b.elems[j+1:]...)
+		nb.hashes = append(append(nb.hashes, b.hashes[:j]...), b.hashes[j+1:]...)
+		if atomic.CompareAndSwapPointer(&t.buckets[i], unsafe.Pointer(b), unsafe.Pointer(nb)) {
+			atomic.AddInt64(&s.count, -1)
+			return true
+		}
+	}
+}
+
+func (s *LockFreeSet) contains(elem interface{}) bool {
+	h := hashOf(elem)
+	_, _, b := s.bucket(h)
+	return b.index(elem, h) >= 0
+}
+
+// grow publishes a table twice the size of t in front of it, unless another goroutine already did.
+func (s *LockFreeSet) grow(t *lockFreeTable) {
+	if s.table() != t {
+		return
+	}
+	// Finish the previous migration first, so tables never chain more than two deep.
+	for i := range t.buckets {
+		if t.bucket(uint64(i)) == nil {
+			t.initBucket(uint64(i))
+		}
+	}
+	atomic.StorePointer(&t.pred, nil)
+	nt := newLockFreeTable(len(t.buckets)*2, t)
+	atomic.CompareAndSwapPointer(&s.head, unsafe.Pointer(t), unsafe.Pointer(nt))
+}
+
+// Range calls fn for each element of the set until fn returns false.
+// Range is weakly consistent, see LockFreeSet.
+func (s *LockFreeSet) Range(fn func(elem interface{}) bool) {
+	t := s.table()
+	for i := range t.buckets {
+		b := t.bucket(uint64(i))
+		if b == nil {
+			b = t.initBucket(uint64(i))
+		}
+		for _, elem := range b.elems {
+			if !fn(elem) {
+				return
+			}
+		}
+	}
+}
+
+// Iter returns an Iterator over the elements of the set.
+// Iteration is weakly consistent, see LockFreeSet. Call Stop if the iteration is abandoned early.
+func (s *LockFreeSet) Iter() *Iterator {
+	iterator, ch, stopCh := newIterator()
+	go func() {
+		defer close(ch)
+		s.Range(func(elem interface{}) bool {
+			select {
+			case <-stopCh:
+				return false
+			case ch <- elem:
+				return true
+			}
+		})
+	}()
+	return iterator
+}
+
+func (s *LockFreeSet) Empty() bool {
+	return s.Cardinality() == 0
+}
+
+func (s *LockFreeSet) Singleton() bool {
+	return s.Cardinality() == 1
+}
+
+func (s *LockFreeSet) Cardinality() int {
+	// The counter trails the buckets, it may briefly go negative while a removal races an addition.
+	if n := atomic.LoadInt64(&s.count); n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+func (s *LockFreeSet) ToSlice() ISlice {
+	result := make(Slice, 0, s.Cardinality())
+	s.Range(func(elem interface{}) bool {
+		result = append(result, elem)
+		return true
+	})
+	return result
+}
+
+func (s *LockFreeSet) Adds(elems ...interface{}) bool {
+	var exist bool
+	for i := 0; i < len(elems); i++ {
+		if !s.add(elems[i]) {
+			exist = true
+		}
+	}
+	return !exist
+}
+
+func (s *LockFreeSet) Removes(elems ...interface{}) bool {
+	var notExist bool
+	for i := 0; i < len(elems); i++ {
+		if !s.remove(elems[i]) {
+			notExist = true
+		}
+	}
+	return !notExist
+}
+
+func (s *LockFreeSet) IsSub(other ISet) bool {
+	if s.Cardinality() > other.Cardinality() {
+		return false
+	}
+	return other.Contains(s.ToSlice().Interface()...)
+}
+
+func (s *LockFreeSet) Unions(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Adds(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *LockFreeSet) Intersections(others ...ISet) ISet {
+	result := NewLockFreeSet()
+	var baseSet ISet = s
+	var diffSets []ISet
+	for _, other := range others {
+		if other.Cardinality() < baseSet.Cardinality() {
+			diffSets = append(diffSets, baseSet)
+			baseSet = other
+		} else {
+			diffSets = append(diffSets, other)
+		}
+	}
+Loop:
+	for _, elem := range baseSet.ToSlice().Interface() {
+		for _, diffSet := range diffSets {
+			if !diffSet.Contains(elem) {
+				continue Loop
+			}
+		}
+		result.Adds(elem)
+	}
+	return result
+}
+
+func (s *LockFreeSet) Complements(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Removes(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+// Clear empties the buckets one at a time, elements added concurrently may survive it.
+func (s *LockFreeSet) Clear() {
+	t := s.table()
+	for i := 0; i < len(t.buckets); i++ {
+		b := t.bucket(uint64(i))
+		if b == nil {
+			b = t.initBucket(uint64(i))
+		}
+		if b.frozen {
+			// t has been superseded, start over on the new head.
+			t, i = s.table(), -1
+			continue
+		}
+		if len(b.elems) == 0 {
+			continue
+		}
+		if atomic.CompareAndSwapPointer(&t.buckets[i], unsafe.Pointer(b), unsafe.Pointer(emptyLockFreeBucket)) {
+			atomic.AddInt64(&s.count, -int64(len(b.elems)))
+		} else {
+			i-- // retry this bucket
+		}
+	}
+}
+
+func (s *LockFreeSet) Contains(elems ...interface{}) bool {
+	for i := 0; i < len(elems); i++ {
+		if !s.contains(elems[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *LockFreeSet) Clone() ISet {
+	return NewLockFreeSet(s.ToSlice().Interface()...)
+}
+
+func (s *LockFreeSet) Equal(other ISet) bool {
+	if other.Cardinality() != s.Cardinality() {
+		return false
+	}
+	return s.Contains(other.ToSlice().Interface()...)
+}
+
+// Pop removes and returns an arbitrary item from the set, nil if none was found.
+func (s *LockFreeSet) Pop() interface{} {
+	for {
+		var candidate interface{}
+		var found bool
+		s.Range(func(elem interface{}) bool {
+			candidate, found = elem, true
+			return false
+		})
+		if !found {
+			return nil
+		}
+		if s.remove(candidate) {
+			return candidate
+		}
+	}
+}
+
+func (s *LockFreeSet) String() string {
+	return formatString(s)
+}
+
+func (s *LockFreeSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", s)
+}
+
+// Hash is weakly consistent, like Range.
+func (s *LockFreeSet) Hash() uint64 {
+	var h setHash
+	s.Range(func(elem interface{}) bool {
+		h.add(elem)
+		return true
+	})
+	return h.sum64()
+}
@@ -0,0 +1,224 @@
+package set
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLockFreeSet_Adds(t *testing.T) {
+	s := NewLockFreeSet()
+	wg := sync.WaitGroup{}
+	for i := range elems {
+		wg.Add(2)
+		go func(i int) {
+			s.Adds(elems[i])
+			wg.Done()
+		}(i)
+		go func(i int) {
+			s.Contains(elems[i])
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	if s.Cardinality() != len(elems) {
+		t.Errorf("Adds.Cardinality() = %v, want %v", s.Cardinality(), len(elems))
+	}
+	if got := len(s.ToSlice().Interface()); got != len(elems) {
+		t.Errorf("Adds.ToSlice() = %v, want %v", got, len(elems))
+	}
+	for i := range elems {
+		if !s.Contains(elems[i]) {
+			t.Errorf("Adds.Contains() = %v, want %v", nil, elems[i])
+		}
+	}
+}
+
+func TestLockFreeSet_Removes(t *testing.T) {
+	s := NewLockFreeSet()
+	wg := sync.WaitGroup{}
+	var hasRemoved int64
+	for i := range elems {
+		wg.Add(2)
+		go func(i int) {
+			s.Adds(elems[i])
+			wg.Done()
+		}(i)
+		go func(i int) {
+			if v := s.Removes(elems[i]); v {
+				atomic.AddInt64(&hasRemoved, 1)
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	if s.Cardinality()+int(hasRemoved) != len(elems) {
+		t.Errorf("Cardinality() = %v, want %v", s.Cardinality()+int(hasRemoved), len(elems))
+	}
+	if got := len(s.ToSlice().Interface()); got != s.Cardinality() {
+		t.Errorf("ToSlice() = %v, want %v", got, s.Cardinality())
+	}
+}
+
+func TestLockFreeSet_Pop(t *testing.T) {
+	s := NewLockFreeSet()
+	for i := range elems {
+		s.Adds(elems[i])
+	}
+	wg := sync.WaitGroup{}
+	var hasPop int64
+	for range elems {
+		wg.Add(1)
+		go func() {
+			if v := s.Pop(); v != nil {
+				atomic.AddInt64(&hasPop, 1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if !s.Empty() || int(hasPop) != len(elems) {
+		t.Errorf("Pop() = %v, want %v", hasPop, len(elems))
+	}
+}
+
+func TestLockFreeSet_Clear(t *testing.T) {
+	s := NewLockFreeSet(1, 2, 3)
+	s.Clear()
+	if !s.Empty() || len(s.ToSlice().Interface()) != 0 {
+		t.Errorf("Clear() = %v, want %v", s, "{}")
+	}
+	if !s.Adds(1) || !s.Equal(NewSet(1)) {
+		t.Errorf("Clear().Adds() = %v, want %v", s, "{1}")
+	}
+}
+
+func TestLockFreeSet_Iter(t *testing.T) {
+	s := NewLockFreeSet()
+	for i := range elems {
+		s.Adds(elems[i])
+	}
+	seen := NewThreadUnsafeSet()
+	for elem := range s.Iter().C {
+		if !seen.Adds(elem) {
+			t.Errorf("Iter() visited %v twice", elem)
+		}
+	}
+	if !seen.Equal(s) {
+		t.Errorf("Iter() = %v, want %v", seen.Cardinality(), s.Cardinality())
+	}
+
+	it := s.Iter()
+	<-it.C
+	it.Stop()
+	if _, ok := <-it.C; ok {
+		t.Errorf("Iter().Stop() did not close C")
+	}
+}
+
+// TestLockFreeSet_IterWeaklyConsistent checks that elements present for the whole iteration are
+// visited exactly once while the table grows underneath it.
+func TestLockFreeSet_IterWeaklyConsistent(t *testing.T) {
+	s := NewLockFreeSet()
+	for i := range elems {
+		s.Adds(elems[i])
+	}
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 20*len(elems); i++ {
+			s.Adds(-i - 1)
+		}
+		close(done)
+	}()
+	seen := NewThreadUnsafeSet()
+	s.Range(func(elem interface{}) bool {
+		if !seen.Adds(elem) {
+			t.Errorf("Range() visited %v twice", elem)
+		}
+		return true
+	})
+	<-done
+	for i := range elems {
+		if !seen.Contains(elems[i]) {
+			t.Errorf("Range() missed %v", elems[i])
+		}
+	}
+}
+
+func TestLockFreeSet_Algebra(t *testing.T) {
+	s := NewLockFreeSet(1, 2, 3, 4)
+	if got, want := s.Unions(NewSet(5)), NewSet(1, 2, 3, 4, 5); !got.Equal(want) {
+		t.Errorf("Unions() = %v, want %v", got, want)
+	}
+	if got, want := s.Intersections(NewSet(2, 3, 9)), NewSet(2, 3); !got.Equal(want) {
+		t.Errorf("Intersections() = %v, want %v", got, want)
+	}
+	if got, want := s.Complements(NewSet(1, 3)), NewSet(2, 4); !got.Equal(want) {
+		t.Errorf("Complements() = %v, want %v", got, want)
+	}
+	if !NewLockFreeSet(1).IsSub(s) {
+		t.Errorf("IsSub() = %v, want %v", false, true)
+	}
+}
+
+func TestShardedSet(t *testing.T) {
+	s := NewShardedSet(8)
+	wg := sync.WaitGroup{}
+	for i := range elems {
+		wg.Add(1)
+		go func(i int) {
+			s.Adds(elems[i])
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	if s.Cardinality() != len(elems) {
+		t.Errorf("Adds.Cardinality() = %v, want %v", s.Cardinality(), len(elems))
+	}
+	if got, want := NewShardedSet(3, 1, 2, 3).Intersections(NewSet(2, 3, 4)), NewSet(2, 3); !got.Equal(want) {
+		t.Errorf("Intersections() = %v, want %v", got, want)
+	}
+	if got, want := NewShardedSet(0, 1, 2, 3).Complements(NewSet(2)), NewSet(1, 3); !got.Equal(want) {
+		t.Errorf("Complements() = %v, want %v", got, want)
+	}
+}
+
+// BenchmarkConcurrentSets compares the concurrent set implementations across read/write mixes:
+//
+//	go test -run=NONE -bench=ConcurrentSets -cpu=1,4,8
+func BenchmarkConcurrentSets(b *testing.B) {
+	impls := []struct {
+		name string
+		new  func() ISet
+	}{
+		{name: "NewSet", new: func() ISet { return NewSet() }},
+		{name: "NewShardedSet", new: func() ISet { return NewShardedSet(32) }},
+		{name: "NewLockFreeSet", new: func() ISet { return NewLockFreeSet() }},
+		{name: "NewCOWSet", new: func() ISet { return NewCOWSet() }},
+	}
+	for _, readPercent := range []int{100, 90, 50, 10} {
+		for _, impl := range impls {
+			b.Run(fmt.Sprintf("reads=%d%%/%s", readPercent, impl.name), func(b *testing.B) {
+				s := impl.new()
+				for i := 0; i < len(elems); i += 2 {
+					s.Adds(elems[i])
+				}
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for i := 0; pb.Next(); i++ {
+						elem := elems[i%len(elems)]
+						switch op := i % 100; {
+						case op < readPercent:
+							s.Contains(elem)
+						case op%2 == 0:
+							s.Adds(elem)
+						default:
+							s.Removes(elem)
+						}
+					}
+				})
+			})
+		}
+	}
+}
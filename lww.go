@@ -0,0 +1,322 @@
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Timestamp is a hybrid logical clock timestamp: a physical time in nanoseconds,
+// and a logical counter ordering the events that share it.
+type Timestamp struct {
+	Wall    int64
+	Logical uint32
+}
+
+// Compare returns -1, 0 or +1 depending on whether t is before, equal to or after u.
+func (t Timestamp) Compare(u Timestamp) int {
+	switch {
+	case t.Wall < u.Wall, t.Wall == u.Wall && t.Logical < u.Logical:
+		return -1
+	case t == u:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%d.%d", t.Wall, t.Logical)
+}
+
+// NewHLC returns a hybrid logical clock reading the physical time from now, time.Now if nil.
+// Inject a fake now to make timestamps deterministic in tests.
+func NewHLC(now func() time.Time) *HLC {
+	if now == nil {
+		now = time.Now
+	}
+	return &HLC{now: now}
+}
+
+// HLC is a hybrid logical clock (Kulkarni et al.): its timestamps follow the physical time,
+// never go backwards, even if the physical clock does, and order every event after the remote
+// events it has observed through Update. It is safe for concurrent use.
+type HLC struct {
+	mu   sync.Mutex
+	now  func() time.Time
+	last Timestamp
+}
+
+// Now returns the timestamp of a local event.
+func (c *HLC) Now() Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pt := c.now().UnixNano(); pt > c.last.Wall {
+		c.last = Timestamp{Wall: pt}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Update advances the clock past a timestamp received from another replica, and returns the timestamp
+// of the receive event.
+func (c *HLC) Update(remote Timestamp) Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pt := c.now().UnixNano()
+	switch {
+	case pt > c.last.Wall && pt > remote.Wall:
+		c.last = Timestamp{Wall: pt}
+	case remote.Wall > c.last.Wall:
+		c.last = Timestamp{Wall: remote.Wall, Logical: remote.Logical + 1}
+	case remote.Wall == c.last.Wall && remote.Logical > c.last.Logical:
+		c.last.Logical = remote.Logical + 1
+	default:
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// LWWBias decides whether an element added and removed at the same timestamp is in an LWWSet.
+type LWWBias int
+
+const (
+	// AddWins keeps the element on ties.
+	AddWins LWWBias = iota
+	// RemoveWins drops the element on ties.
+	RemoveWins
+)
+
+// NewLWWSet returns an empty last-writer-wins element set CRDT replica, stamping its operations with clock,
+// a new HLC reading time.Now if nil. All the replicas that merge with each other must use the same bias.
+func NewLWWSet(bias LWWBias, clock *HLC) *LWWSet {
+	if clock == nil {
+		clock = NewHLC(nil)
+	}
+	return &LWWSet{bias: bias, clock: clock, adds: make(map[interface{}]Timestamp), removes: make(map[interface{}]Timestamp)}
+}
+
+// LWWSet is a last-writer-wins element set (LWW-Element-Set) CRDT: it remembers the latest timestamp
+// each element was added and removed at, and an element is in the set if its latest addition is
+// after its latest removal, ties are broken by the bias. Unlike a TwoPhaseSet, elements can be removed
+// and added again; unlike an ORSet, the outcome of concurrent operations depends on the clocks.
+// It is not thread safe.
+type LWWSet struct {
+	bias    LWWBias
+	clock   *HLC
+	adds    map[interface{}]Timestamp
+	removes map[interface{}]Timestamp
+}
+
+// Adds adds elements to the replica at the current time. Returns whether all the items was added.
+func (s *LWWSet) Adds(elems ...interface{}) bool {
+	return s.AddsAt(s.clock.Now(), elems...)
+}
+
+// AddsAt adds elements to the replica at the given time, for instance to replay an operation
+// received from another replica. Returns whether all the items was added.
+func (s *LWWSet) AddsAt(ts Timestamp, elems ...interface{}) bool {
+	var exist bool
+	for i := 0; i < len(elems); i++ {
+		if s.Contains(elems[i]) {
+			exist = true
+		}
+		if last, ok := s.adds[elems[i]]; !ok || ts.Compare(last) > 0 {
+			s.adds[elems[i]] = ts
+		}
+	}
+	return !exist
+}
+
+// Removes removes elements from the replica at the current time. Returns whether all the items was removed.
+func (s *LWWSet) Removes(elems ...interface{}) bool {
+	return s.RemovesAt(s.clock.Now(), elems...)
+}
+
+// RemovesAt removes elements from the replica at the given time. Returns whether all the items was removed.
+func (s *LWWSet) RemovesAt(ts Timestamp, elems ...interface{}) bool {
+	var notExist bool
+	for i := 0; i < len(elems); i++ {
+		if !s.Contains(elems[i]) {
+			notExist = true
+		}
+		if last, ok := s.removes[elems[i]]; !ok || ts.Compare(last) > 0 {
+			s.removes[elems[i]] = ts
+		}
+	}
+	return !notExist
+}
+
+func (s *LWWSet) contains(elem interface{}) bool {
+	added, ok := s.adds[elem]
+	if !ok {
+		return false
+	}
+	removed, ok := s.removes[elem]
+	if !ok {
+		return true
+	}
+	switch added.Compare(removed) {
+	case 1:
+		return true
+	case 0:
+		return s.bias == AddWins
+	default:
+		return false
+	}
+}
+
+// Contains returns whether the given items are all in the set.
+func (s *LWWSet) Contains(elems ...interface{}) bool {
+	for i := 0; i < len(elems); i++ {
+		if !s.contains(elems[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cardinality returns the number of elements in the set.
+func (s *LWWSet) Cardinality() int {
+	var n int
+	for elem := range s.adds {
+		if s.contains(elem) {
+			n++
+		}
+	}
+	return n
+}
+
+// Value returns a copy of the current elements, see NewThreadUnsafeSet.
+func (s *LWWSet) Value() ISet {
+	result := make(threadUnsafeSet)
+	for elem := range s.adds {
+		if s.contains(elem) {
+			result[elem] = struct{}{}
+		}
+	}
+	return &result
+}
+
+// Merge folds the state of another replica into s, keeping the latest timestamps of both,
+// and advances the clock of s past them. Merge is commutative, associative and idempotent.
+func (s *LWWSet) Merge(other *LWWSet) {
+	var latest Timestamp
+	for elem, ts := range other.adds {
+		if last, ok := s.adds[elem]; !ok || ts.Compare(last) > 0 {
+			s.adds[elem] = ts
+		}
+		if ts.Compare(latest) > 0 {
+			latest = ts
+		}
+	}
+	for elem, ts := range other.removes {
+		if last, ok := s.removes[elem]; !ok || ts.Compare(last) > 0 {
+			s.removes[elem] = ts
+		}
+		if ts.Compare(latest) > 0 {
+			latest = ts
+		}
+	}
+	if latest != (Timestamp{}) {
+		s.clock.Update(latest)
+	}
+}
+
+// Clone returns an independent copy of the replica, sharing its clock.
+func (s *LWWSet) Clone() *LWWSet {
+	result := NewLWWSet(s.bias, s.clock)
+	for elem, ts := range s.adds {
+		result.adds[elem] = ts
+	}
+	for elem, ts := range s.removes {
+		result.removes[elem] = ts
+	}
+	return result
+}
+
+// Equal returns whether two replicas have the same state, timestamps included.
+func (s *LWWSet) Equal(other *LWWSet) bool {
+	return equalTimestamps(s.adds, other.adds) && equalTimestamps(s.removes, other.removes)
+}
+
+func equalTimestamps(a, b map[interface{}]Timestamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for elem, ts := range a {
+		if other, ok := b[elem]; !ok || other != ts {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *LWWSet) String() string {
+	return s.Value().String()
+}
+
+// MarshalBinary encodes the replica state, see WriteSet for the supported element types.
+// The bias and the clock are not encoded.
+func (s *LWWSet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, m := range []map[interface{}]Timestamp{s.adds, s.removes} {
+		buf.Write(appendUvarint(nil, uint64(len(m))))
+		for elem, ts := range m {
+			if err := writeElem(&buf, elem, nil); err != nil {
+				return nil, err
+			}
+			buf.Write(appendUvarint(appendVarint(nil, ts.Wall), uint64(ts.Logical)))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the replica state with one encoded by MarshalBinary,
+// and advances the clock past its timestamps.
+func (s *LWWSet) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	result := NewLWWSet(s.bias, s.clock)
+	for _, m := range []map[interface{}]Timestamp{result.adds, result.removes} {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return lwwDecodeErr(err)
+		}
+		for i := uint64(0); i < n; i++ {
+			elem, _, err := readElem(r, nil)
+			if err != nil {
+				return lwwDecodeErr(err)
+			}
+			wall, err := binary.ReadVarint(r)
+			if err != nil {
+				return lwwDecodeErr(err)
+			}
+			logical, err := binary.ReadUvarint(r)
+			if err != nil {
+				return lwwDecodeErr(err)
+			}
+			if logical > 1<<32-1 {
+				return lwwDecodeErr(errors.New("logical clock overflow"))
+			}
+			m[elem] = Timestamp{Wall: wall, Logical: uint32(logical)}
+		}
+	}
+	if r.Len() != 0 {
+		return lwwDecodeErr(errors.New("trailing bytes"))
+	}
+	s.clock, s.adds, s.removes = result.clock, result.adds, result.removes
+	s.Merge(result) // advances the clock
+	return nil
+}
+
+func lwwDecodeErr(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("go-set: LWWSet UnmarshalBinary() err, %v", err)
+}
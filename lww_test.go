@@ -0,0 +1,126 @@
+package set
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a physical clock that only moves when told to.
+func fakeClock(start int64) (now func() time.Time, set func(int64)) {
+	t := start
+	return func() time.Time { return time.Unix(0, t) }, func(nt int64) { t = nt }
+}
+
+func TestHLC(t *testing.T) {
+	now, set := fakeClock(100)
+	c := NewHLC(now)
+	tests := []struct {
+		name   string
+		wall   int64
+		remote *Timestamp
+		want   Timestamp
+	}{
+		{name: "physical", wall: 100, want: Timestamp{Wall: 100}},
+		{name: "same physical", wall: 100, want: Timestamp{Wall: 100, Logical: 1}},
+		{name: "physical backwards", wall: 50, want: Timestamp{Wall: 100, Logical: 2}},
+		{name: "remote ahead", wall: 100, remote: &Timestamp{Wall: 200, Logical: 7}, want: Timestamp{Wall: 200, Logical: 8}},
+		{name: "remote behind", wall: 150, remote: &Timestamp{Wall: 120}, want: Timestamp{Wall: 200, Logical: 9}},
+		{name: "remote same wall", wall: 150, remote: &Timestamp{Wall: 200, Logical: 20}, want: Timestamp{Wall: 200, Logical: 21}},
+		{name: "physical ahead", wall: 300, remote: &Timestamp{Wall: 200}, want: Timestamp{Wall: 300}},
+	}
+	for _, tt := range tests {
+		set(tt.wall)
+		var got Timestamp
+		if tt.remote != nil {
+			got = c.Update(*tt.remote)
+		} else {
+			got = c.Now()
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func randomLWWSet(r *rand.Rand) *LWWSet {
+	s := NewLWWSet(RemoveWins, NewHLC(func() time.Time { return time.Unix(0, r.Int63n(5)) }))
+	for i := r.Intn(15); i > 0; i-- {
+		if r.Intn(3) == 0 {
+			s.Removes(r.Intn(10))
+		} else {
+			s.Adds(r.Intn(10))
+		}
+	}
+	return s
+}
+
+func mergedLWWSet(a, b *LWWSet) *LWWSet {
+	result := a.Clone()
+	result.Merge(b)
+	return result
+}
+
+func TestLWWSet_MergeProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < crdtPropertyRounds; i++ {
+		a, b, c := randomLWWSet(r), randomLWWSet(r), randomLWWSet(r)
+		if !mergedLWWSet(a, b).Equal(mergedLWWSet(b, a)) {
+			t.Fatalf("Merge() not commutative for %v, %v", a, b)
+		}
+		if !mergedLWWSet(mergedLWWSet(a, b), c).Equal(mergedLWWSet(a, mergedLWWSet(b, c))) {
+			t.Fatalf("Merge() not associative for %v, %v, %v", a, b, c)
+		}
+		if !mergedLWWSet(a, a).Equal(a) || !mergedLWWSet(mergedLWWSet(a, b), b).Equal(mergedLWWSet(a, b)) {
+			t.Fatalf("Merge() not idempotent for %v, %v", a, b)
+		}
+	}
+}
+
+func TestLWWSet(t *testing.T) {
+	now, set := fakeClock(10)
+	a := NewLWWSet(AddWins, NewHLC(now))
+	if !a.Adds(1, 2, 3) || a.Adds(1) || !a.Removes(3) || a.Removes(3) || !a.Adds(3) {
+		t.Errorf("Adds(), Removes() = %v, want %v", a, "{1,2,3}")
+	}
+
+	// b's physical clock runs late, but merging a moves its clock past a's timestamps.
+	nowB, _ := fakeClock(0)
+	b := NewLWWSet(AddWins, NewHLC(nowB))
+	b.Merge(a)
+	b.Removes(1)
+	a.Merge(b)
+	if want := NewSet(2, 3); !a.Value().Equal(want) || a.Cardinality() != 2 || a.Contains(1) {
+		t.Errorf("Merge() = %v, want %v", a, want)
+	}
+
+	// Ties are broken by the bias.
+	ts := Timestamp{Wall: 1000}
+	for _, tt := range []struct {
+		bias LWWBias
+		want bool
+	}{{bias: AddWins, want: true}, {bias: RemoveWins, want: false}} {
+		s := NewLWWSet(tt.bias, nil)
+		s.AddsAt(ts, "x")
+		s.RemovesAt(ts, "x")
+		if got := s.Contains("x"); got != tt.want {
+			t.Errorf("Contains() with bias %v = %v, want %v", tt.bias, got, tt.want)
+		}
+	}
+
+	set(20)
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	got := &LWWSet{}
+	if err := got.UnmarshalBinary(data); err != nil || !got.Equal(a) {
+		t.Errorf("UnmarshalBinary() = %v, %v, want %v", got, err, a)
+	}
+	if !got.Adds(4) || !got.Contains(4) {
+		t.Errorf("Adds() after UnmarshalBinary() = %v, want 4 in it", got)
+	}
+	if err := got.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Errorf("UnmarshalBinary() error = %v, want an error", err)
+	}
+}
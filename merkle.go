@@ -0,0 +1,271 @@
+package set
+
+import (
+	"fmt"
+)
+
+// MaxMerkleDepth is the maximum depth of a MerkleIndex, it has 2^depth buckets.
+const MaxMerkleDepth = 20
+
+// MerklePeer is the remote side of a Merkle sync, usually a client of a peer process;
+// a MerkleIndex is the in-process implementation.
+type MerklePeer interface {
+	// Depth returns the depth of the peer tree.
+	Depth() int
+	// Hashes returns the hashes of the given nodes, see MerkleIndex.NodeHash.
+	Hashes(nodes []int) ([]uint64, error)
+	// Buckets returns the elements of the given buckets.
+	Buckets(buckets []int) ([][]interface{}, error)
+}
+
+// NewMerkleIndex returns a Merkle index over s with 2^depth buckets, see WriteSet for the supported
+// element types. From then on, s must only be modified through the index.
+func NewMerkleIndex(s ISet, depth int) (*MerkleIndex, error) {
+	if depth < 0 || depth > MaxMerkleDepth {
+		return nil, fmt.Errorf("go-set: NewMerkleIndex() err, depth %d out of [0, %d]", depth, MaxMerkleDepth)
+	}
+	m := &MerkleIndex{
+		s:       s,
+		depth:   depth,
+		nodes:   make([]uint64, 2<<depth),
+		sums:    make([]uint64, 1<<depth),
+		buckets: make([]threadUnsafeSet, 1<<depth),
+	}
+	for _, elem := range s.ToSlice().Interface() {
+		b, h, err := m.locate(elem)
+		if err != nil {
+			return nil, err
+		}
+		m.insert(b, h, elem)
+	}
+	for node := len(m.nodes) - 1; node > 0; node-- {
+		m.rehash(node)
+	}
+	return m, nil
+}
+
+// MerkleIndex fingerprints a set with a Merkle tree, to compare and sync sets living in different processes.
+//
+// Elements are bucketed by the top bits of a hash of their encoding, which is the same in every process.
+// A bucket hash only depends on the elements in it, not on their order, and each inner node hashes its
+// two children, so two sets are equal if their root hashes are, and the buckets that differ are found
+// by drilling down the subtrees whose hashes differ. It is not thread safe.
+//
+// Examples:
+//
+//	local, _ := NewMerkleIndex(a, 10)
+//	if local.RootHash() != remoteRootHash {
+//		local.Sync(peer) // only fetches the buckets that differ
+//	}
+type MerkleIndex struct {
+	s       ISet
+	depth   int
+	nodes   []uint64 // node hashes, the root is 1 and the children of n are 2n and 2n+1
+	sums    []uint64 // sum of the element hashes of each bucket
+	buckets []threadUnsafeSet
+}
+
+func (m *MerkleIndex) locate(elem interface{}) (bucket int, h uint64, err error) {
+	enc, err := appendElem(nil, elem)
+	if err != nil {
+		return 0, 0, err
+	}
+	h = hashBytes(enc)
+	if m.depth == 0 {
+		return 0, h, nil
+	}
+	return int(h >> (64 - m.depth)), h, nil
+}
+
+func (m *MerkleIndex) insert(b int, h uint64, elem interface{}) {
+	if m.buckets[b] == nil {
+		m.buckets[b] = make(threadUnsafeSet)
+	}
+	m.buckets[b][elem] = struct{}{}
+	m.sums[b] += h
+}
+
+// rehash recomputes the hash of a node from its bucket or its children. Empty subtrees hash to 0.
+func (m *MerkleIndex) rehash(node int) {
+	h := newFnv64()
+	if leaves := 1 << m.depth; node >= leaves {
+		b := node - leaves
+		if len(m.buckets[b]) == 0 {
+			m.nodes[node] = 0
+			return
+		}
+		h.writeUint64(m.sums[b])
+		h.writeUint64(uint64(len(m.buckets[b])))
+	} else {
+		if m.nodes[2*node] == 0 && m.nodes[2*node+1] == 0 {
+			m.nodes[node] = 0
+			return
+		}
+		h.writeUint64(m.nodes[2*node])
+		h.writeUint64(m.nodes[2*node+1])
+	}
+	m.nodes[node] = h.sum()
+}
+
+func (m *MerkleIndex) rehashPath(b int) {
+	for node := 1<<m.depth + b; node > 0; node /= 2 {
+		m.rehash(node)
+	}
+}
+
+// Set returns the indexed set.
+func (m *MerkleIndex) Set() ISet {
+	return m.s
+}
+
+// Depth returns the depth of the tree.
+func (m *MerkleIndex) Depth() int {
+	return m.depth
+}
+
+// RootHash returns the fingerprint of the whole set, 0 if it is empty.
+func (m *MerkleIndex) RootHash() uint64 {
+	return m.nodes[1]
+}
+
+// NodeHash returns the hash of a node: 1 is the root, the children of node n are 2n and 2n+1,
+// and the leaf of bucket b is 2^depth + b.
+func (m *MerkleIndex) NodeHash(node int) uint64 {
+	return m.nodes[node]
+}
+
+// Hashes returns the hashes of the given nodes.
+func (m *MerkleIndex) Hashes(nodes []int) ([]uint64, error) {
+	result := make([]uint64, len(nodes))
+	for i, node := range nodes {
+		if node < 1 || node >= len(m.nodes) {
+			return nil, fmt.Errorf("go-set: MerkleIndex Hashes() err, node %d out of [1, %d)", node, len(m.nodes))
+		}
+		result[i] = m.nodes[node]
+	}
+	return result, nil
+}
+
+// Buckets returns the elements of the given buckets.
+func (m *MerkleIndex) Buckets(buckets []int) ([][]interface{}, error) {
+	result := make([][]interface{}, len(buckets))
+	for i, b := range buckets {
+		if b < 0 || b >= len(m.buckets) {
+			return nil, fmt.Errorf("go-set: MerkleIndex Buckets() err, bucket %d out of [0, %d)", b, len(m.buckets))
+		}
+		result[i] = m.buckets[b].ToSlice().Interface()
+	}
+	return result, nil
+}
+
+// Adds adds elements to the set and the index. Returns whether all the items was added.
+func (m *MerkleIndex) Adds(elems ...interface{}) (bool, error) {
+	var exist bool
+	for i := 0; i < len(elems); i++ {
+		b, h, err := m.locate(elems[i])
+		if err != nil {
+			return false, err
+		}
+		if _, ok := m.buckets[b][elems[i]]; ok {
+			exist = true
+			continue
+		}
+		m.s.Adds(elems[i])
+		m.insert(b, h, elems[i])
+		m.rehashPath(b)
+	}
+	return !exist, nil
+}
+
+// Removes removes elements from the set and the index. Returns whether all the items was removed.
+func (m *MerkleIndex) Removes(elems ...interface{}) (bool, error) {
+	var notExist bool
+	for i := 0; i < len(elems); i++ {
+		b, h, err := m.locate(elems[i])
+		if err != nil {
+			return false, err
+		}
+		if _, ok := m.buckets[b][elems[i]]; !ok {
+			notExist = true
+			continue
+		}
+		m.s.Removes(elems[i])
+		delete(m.buckets[b], elems[i])
+		m.sums[b] -= h
+		m.rehashPath(b)
+	}
+	return !notExist, nil
+}
+
+// DiffBuckets returns the buckets whose contents differ from the peer ones, comparing the trees
+// level by level from the root and only descending into the subtrees that differ:
+// it asks the peer for depth+1 batches of hashes at most.
+func (m *MerkleIndex) DiffBuckets(peer MerklePeer) ([]int, error) {
+	if d := peer.Depth(); d != m.depth {
+		return nil, fmt.Errorf("go-set: MerkleIndex DiffBuckets() err, depth %d, the peer has %d", m.depth, d)
+	}
+	frontier := []int{1}
+	for level := 0; len(frontier) > 0; level++ {
+		hashes, err := peer.Hashes(frontier)
+		if err != nil {
+			return nil, err
+		}
+		if len(hashes) != len(frontier) {
+			return nil, fmt.Errorf("go-set: MerkleIndex DiffBuckets() err, asked for %d hashes, the peer returned %d", len(frontier), len(hashes))
+		}
+		var next []int
+		for i, node := range frontier {
+			if hashes[i] == m.nodes[node] {
+				continue
+			}
+			if level == m.depth {
+				next = append(next, node-1<<m.depth)
+			} else {
+				next = append(next, 2*node, 2*node+1)
+			}
+		}
+		if level == m.depth {
+			return next, nil
+		}
+		frontier = next
+	}
+	return nil, nil
+}
+
+// Sync makes the set equal to the peer one, fetching only the buckets that differ.
+// Returns the number of elements transferred.
+func (m *MerkleIndex) Sync(peer MerklePeer) (int, error) {
+	diff, err := m.DiffBuckets(peer)
+	if err != nil || len(diff) == 0 {
+		return 0, err
+	}
+	contents, err := peer.Buckets(diff)
+	if err != nil {
+		return 0, err
+	}
+	if len(contents) != len(diff) {
+		return 0, fmt.Errorf("go-set: MerkleIndex Sync() err, asked for %d buckets, the peer returned %d", len(diff), len(contents))
+	}
+	for _, content := range contents {
+		if err := CheckHashable(content...); err != nil {
+			return 0, fmt.Errorf("go-set: MerkleIndex Sync() err, the peer returned %w", err)
+		}
+	}
+	var transferred int
+	for i, b := range diff {
+		transferred += len(contents[i])
+		remote := make(threadUnsafeSet, len(contents[i]))
+		remote.Adds(contents[i]...)
+		for elem := range m.buckets[b] {
+			if _, ok := remote[elem]; !ok {
+				if _, err := m.Removes(elem); err != nil {
+					return transferred, err
+				}
+			}
+		}
+		if _, err := m.Adds(contents[i]...); err != nil {
+			return transferred, err
+		}
+	}
+	return transferred, nil
+}
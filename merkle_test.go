@@ -0,0 +1,137 @@
+package set
+
+import (
+	"testing"
+)
+
+// countingPeer records how much a sync asks of the peer.
+type countingPeer struct {
+	MerklePeer
+	hashCalls, buckets int
+}
+
+func (p *countingPeer) Hashes(nodes []int) ([]uint64, error) {
+	p.hashCalls++
+	return p.MerklePeer.Hashes(nodes)
+}
+
+func (p *countingPeer) Buckets(buckets []int) ([][]interface{}, error) {
+	p.buckets += len(buckets)
+	return p.MerklePeer.Buckets(buckets)
+}
+
+func TestMerkleIndex_RootHash(t *testing.T) {
+	ordered, reversed := NewSet(), NewSet()
+	for i := range elems {
+		ordered.Adds(elems[i])
+		reversed.Adds(elems[len(elems)-1-i])
+	}
+	a, _ := NewMerkleIndex(ordered, 8)
+	b, _ := NewMerkleIndex(reversed, 8)
+	if a.RootHash() != b.RootHash() || a.RootHash() == 0 {
+		t.Errorf("RootHash() = %v, %v, want equal and not 0", a.RootHash(), b.RootHash())
+	}
+	root := a.RootHash()
+	if ok, err := a.Adds(-1); !ok || err != nil || a.RootHash() == root {
+		t.Errorf("Adds() = %v, %v, want the root hash to change", ok, err)
+	}
+	if ok, err := a.Removes(-1); !ok || err != nil || a.RootHash() != root {
+		t.Errorf("Removes() = %v, %v, want the root hash back to %v", ok, err, root)
+	}
+	if ok, _ := a.Adds(elems[0]); ok {
+		t.Errorf("Adds() of an existing element = %v, want false", ok)
+	}
+	empty, _ := NewMerkleIndex(NewSet(), 4)
+	if empty.RootHash() != 0 {
+		t.Errorf("RootHash() of an empty set = %v, want 0", empty.RootHash())
+	}
+	if _, err := NewMerkleIndex(NewSet(struct{}{}), 4); err == nil {
+		t.Errorf("NewMerkleIndex() error = %v, want an error for an unencodable element", err)
+	}
+	if _, err := NewMerkleIndex(NewSet(), MaxMerkleDepth+1); err == nil {
+		t.Errorf("NewMerkleIndex() error = %v, want an error for the depth", err)
+	}
+}
+
+func TestMerkleIndex_Sync(t *testing.T) {
+	remoteSet := NewSet()
+	for i := 0; i < 10000; i++ {
+		remoteSet.Adds(i)
+	}
+	localSet := remoteSet.Clone()
+	localSet.Removes(1, 2, 3)
+	localSet.Adds("stale")
+	remote, _ := NewMerkleIndex(remoteSet, 10)
+	local, _ := NewMerkleIndex(localSet, 10)
+
+	peer := &countingPeer{MerklePeer: remote}
+	diff, err := local.DiffBuckets(peer)
+	if err != nil || len(diff) == 0 || len(diff) > 4 {
+		t.Errorf("DiffBuckets() = %v, %v, want at most 4 buckets", diff, err)
+	}
+	if peer.hashCalls != local.Depth()+1 {
+		t.Errorf("DiffBuckets() asked for %v batches of hashes, want %v", peer.hashCalls, local.Depth()+1)
+	}
+
+	transferred, err := local.Sync(peer)
+	if err != nil || !localSet.Equal(remoteSet) || local.RootHash() != remote.RootHash() {
+		t.Fatalf("Sync() = %v, %v, want the sets equal", transferred, err)
+	}
+	if transferred > 100 || peer.buckets > 4 {
+		t.Errorf("Sync() transferred %v elements in %v buckets, want only the differing buckets", transferred, peer.buckets)
+	}
+	if transferred, err := local.Sync(peer); transferred != 0 || err != nil {
+		t.Errorf("Sync() of equal sets = %v, %v, want 0", transferred, err)
+	}
+
+	other, _ := NewMerkleIndex(remoteSet, 4)
+	if _, err := local.Sync(other); err == nil {
+		t.Errorf("Sync() error = %v, want an error for different depths", err)
+	}
+}
+
+// truncatingPeer returns short replies, like a buggy or hostile remote peer.
+type truncatingPeer struct {
+	MerklePeer
+	hashes, buckets bool
+}
+
+func (p *truncatingPeer) Hashes(nodes []int) ([]uint64, error) {
+	hashes, err := p.MerklePeer.Hashes(nodes)
+	if p.hashes {
+		hashes = hashes[:len(hashes)-1]
+	}
+	return hashes, err
+}
+
+func (p *truncatingPeer) Buckets(buckets []int) ([][]interface{}, error) {
+	contents, err := p.MerklePeer.Buckets(buckets)
+	if p.buckets {
+		contents = contents[:len(contents)-1]
+	}
+	return contents, err
+}
+
+func TestMerkleIndex_BadPeer(t *testing.T) {
+	remote, _ := NewMerkleIndex(NewSet(1, 2, 3), 4)
+	for _, peer := range []*truncatingPeer{{MerklePeer: remote, hashes: true}, {MerklePeer: remote, buckets: true}} {
+		local, _ := NewMerkleIndex(NewSet(1), 4)
+		if n, err := local.Sync(peer); err == nil {
+			t.Errorf("Sync() with a short reply = %v, %v, want an error", n, err)
+		}
+	}
+	local, _ := NewMerkleIndex(NewSet(1), 4)
+	if _, err := local.Sync(&unhashablePeer{remote}); err == nil {
+		t.Errorf("Sync() with unhashable elements error = %v, want an error", err)
+	}
+}
+
+type unhashablePeer struct {
+	MerklePeer
+}
+
+func (p *unhashablePeer) Buckets(buckets []int) ([][]interface{}, error) {
+	contents := make([][]interface{}, len(buckets))
+	contents[0] = []interface{}{[]int{1}}
+	return contents, nil
+}
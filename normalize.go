@@ -0,0 +1,437 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ErrRejectedNumber is wrapped by the errors reporting numbers rejected by NumberRules, see NumberError.
+var ErrRejectedNumber = errors.New("go-set: number rejected by the normalization rules")
+
+// NumberError reports an element rejected by the NumberRules of a normalizing set.
+type NumberError struct {
+	// Index is the position of the element among the arguments.
+	Index int
+	// Value is the element.
+	Value interface{}
+	// Reason tells which rule rejected it.
+	Reason string
+}
+
+func (e *NumberError) Error() string {
+	return fmt.Sprintf("go-set: element %d, %v %s", e.Index, e.Value, e.Reason)
+}
+
+func (e *NumberError) Unwrap() error {
+	return ErrRejectedNumber
+}
+
+// NaNRule decides how a normalizing set treats NaN elements.
+type NaNRule int
+
+const (
+	// NaNSingle makes every NaN the same element, unlike ==.
+	NaNSingle NaNRule = iota
+	// NaNReject rejects NaN elements.
+	NaNReject
+)
+
+// OverflowRule decides how a normalizing set treats integral floats that neither int64 nor uint64 can hold.
+type OverflowRule int
+
+const (
+	// OverflowKeepFloat keeps them as float64 elements.
+	OverflowKeepFloat OverflowRule = iota
+	// OverflowReject rejects them.
+	OverflowReject
+)
+
+// NumberRules configures the numeric normalization of NewNormalizingSet. The zero value makes
+// int(1), int64(1), uint8(1) and float64(1) the same element, and every NaN the same element.
+type NumberRules struct {
+	// KeepFloats keeps floats apart from integers: float64(1) and int(1) are different elements,
+	// float32(1) and float64(1) are still the same one.
+	KeepFloats bool
+	// NaN decides how NaN elements are treated.
+	NaN NaNRule
+	// Overflow decides how integral floats out of the range of int64 and uint64 are treated.
+	Overflow OverflowRule
+}
+
+// nanElem is the canonical form of NaN when NaNs are a single element: NaN cannot be a map key, since NaN != NaN.
+type nanElem struct{}
+
+// normalize returns the canonical form of elem: integers become int64, or uint64 above math.MaxInt64,
+// floats become float64, or an integer if they are integral, complex numbers with no imaginary part
+// become floats, other complex numbers complex128. Other elements are returned as is.
+func (r NumberRules) normalize(elem interface{}) (interface{}, error) {
+	switch x := elem.(type) {
+	case int:
+		return int64(x), nil
+	case int8:
+		return int64(x), nil
+	case int16:
+		return int64(x), nil
+	case int32:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case uint:
+		return normalizeUint(uint64(x)), nil
+	case uint8:
+		return int64(x), nil
+	case uint16:
+		return int64(x), nil
+	case uint32:
+		return int64(x), nil
+	case uint64:
+		return normalizeUint(x), nil
+	case float32:
+		return r.normalizeFloat(float64(x))
+	case float64:
+		return r.normalizeFloat(x)
+	case complex64:
+		if imag(x) == 0 {
+			return r.normalizeFloat(float64(real(x)))
+		}
+		return complex128(x), nil
+	case complex128:
+		if imag(x) == 0 {
+			return r.normalizeFloat(real(x))
+		}
+		return x, nil
+	}
+	return elem, nil
+}
+
+func normalizeUint(x uint64) interface{} {
+	if x <= math.MaxInt64 {
+		return int64(x)
+	}
+	return x
+}
+
+func (r NumberRules) normalizeFloat(f float64) (interface{}, error) {
+	switch {
+	case math.IsNaN(f):
+		if r.NaN == NaNReject {
+			return nil, errors.New("is rejected")
+		}
+		return nanElem{}, nil
+	case r.KeepFloats || math.IsInf(f, 0) || f != math.Trunc(f):
+		return f + 0, nil // +0 turns -0 into 0
+	case f >= math.MinInt64 && f < math.MaxInt64:
+		return int64(f), nil
+	case f > 0 && f < math.MaxUint64:
+		return uint64(f), nil
+	case r.Overflow == OverflowReject:
+		return nil, errors.New("overflows int64 and uint64")
+	}
+	return f, nil
+}
+
+// normalizeAll normalizes elems into a new slice, reporting the index of a rejected one.
+func (r NumberRules) normalizeAll(elems []interface{}) ([]interface{}, error) {
+	result := make([]interface{}, len(elems))
+	for i := 0; i < len(elems); i++ {
+		elem, err := r.normalize(elems[i])
+		if err != nil {
+			return nil, &NumberError{Index: i, Value: elems[i], Reason: err.Error()}
+		}
+		result[i] = elem
+	}
+	return result, nil
+}
+
+// denormalize turns a stored element back into a value callers can use.
+func denormalize(elem interface{}) interface{} {
+	if _, ok := elem.(nanElem); ok {
+		return math.NaN()
+	}
+	return elem
+}
+
+// NewNormalizingSet returns a thread safe set that normalizes numeric elements according to rules,
+// so that numbers of different types but equal values, as decoded from JSON, SQL or protobuf,
+// are the same element: Contains(1) finds int64(1) and float64(1).
+// Numbers are stored in their canonical form: int64, uint64 above math.MaxInt64, float64 or complex128;
+// ToSlice converts numbers leniently: Int() and the other numeric conversions accept any numeric type
+// that converts exactly, as the As methods do.
+// Named numeric types and non numeric elements are stored as is.
+// It returns a *NumberError for elements the rules reject, and Adds panics with it,
+// use TryAdds to get the error instead.
+// Examples:
+// s, _ := NewNormalizingSet(NumberRules{}, int64(1), 2.0)
+// s.Contains(1, 2)       // true
+// s.ToSlice().Int()      // [1 2]
+func NewNormalizingSet(rules NumberRules, elems ...interface{}) (ISet, error) {
+	canonical, err := rules.normalizeAll(elems)
+	if err != nil {
+		return nil, err
+	}
+	return &normalizingSet{rules: rules, m: NewThreadUnsafeSet(canonical...).(*threadUnsafeSet)}, nil
+}
+
+type normalizingSet struct {
+	rwm   sync.RWMutex
+	rules NumberRules
+	m     *threadUnsafeSet // canonical elements
+}
+
+// canonical normalizes elements that are looked up: rejected ones are replaced by a value no set holds.
+func (s *normalizingSet) canonical(elems []interface{}) []interface{} {
+	result := make([]interface{}, len(elems))
+	for i := 0; i < len(elems); i++ {
+		elem, err := s.rules.normalize(elems[i])
+		if err != nil {
+			elem = &elem // a new pointer is in no set
+		}
+		result[i] = elem
+	}
+	return result
+}
+
+func (s *normalizingSet) Empty() bool {
+	return s.Cardinality() == 0
+}
+
+func (s *normalizingSet) Singleton() bool {
+	return s.Cardinality() == 1
+}
+
+func (s *normalizingSet) Cardinality() int {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	return s.m.Cardinality()
+}
+
+// ToSlice returns a slice whose numeric conversions are lenient, see lenientSlice.
+func (s *normalizingSet) ToSlice() ISlice {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	result := make(Slice, 0, s.m.Cardinality())
+	for elem := range *s.m {
+		result = append(result, denormalize(elem))
+	}
+	return lenientSlice{result}
+}
+
+func (s *normalizingSet) checkElems(elems []interface{}, adding bool) error {
+	if err := CheckHashable(elems...); err != nil || !adding {
+		return err
+	}
+	_, err := s.rules.normalizeAll(elems)
+	return err
+}
+
+func (s *normalizingSet) Adds(elems ...interface{}) bool {
+	canonical, err := s.rules.normalizeAll(elems)
+	if err != nil {
+		panic(err)
+	}
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	return s.m.Adds(canonical...)
+}
+
+func (s *normalizingSet) Removes(elems ...interface{}) bool {
+	canonical := s.canonical(elems)
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	return s.m.Removes(canonical...)
+}
+
+func (s *normalizingSet) IsSub(other ISet) bool {
+	if s.Cardinality() > other.Cardinality() {
+		return false
+	}
+	return other.Contains(s.ToSlice().Interface()...)
+}
+
+func (s *normalizingSet) Unions(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Adds(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *normalizingSet) Intersections(others ...ISet) ISet {
+	result, _ := NewNormalizingSet(s.rules)
+Loop:
+	for _, elem := range s.ToSlice().Interface() {
+		for _, other := range others {
+			if !other.Contains(elem) {
+				continue Loop
+			}
+		}
+		result.Adds(elem)
+	}
+	return result
+}
+
+func (s *normalizingSet) Complements(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Removes(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *normalizingSet) Clear() {
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	s.m = NewThreadUnsafeSet().(*threadUnsafeSet)
+}
+
+func (s *normalizingSet) Contains(elems ...interface{}) bool {
+	canonical := s.canonical(elems)
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	return s.m.Contains(canonical...)
+}
+
+func (s *normalizingSet) Clone() ISet {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	return &normalizingSet{rules: s.rules, m: s.m.Clone().(*threadUnsafeSet)}
+}
+
+func (s *normalizingSet) Equal(other ISet) bool {
+	if other.Cardinality() != s.Cardinality() {
+		return false
+	}
+	return s.Contains(other.ToSlice().Interface()...)
+}
+
+func (s *normalizingSet) Pop() interface{} {
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	elem := s.m.Pop()
+	return denormalize(elem)
+}
+
+func (s *normalizingSet) String() string {
+	return formatString(s)
+}
+
+func (s *normalizingSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", s)
+}
+
+func (s *normalizingSet) Hash() uint64 {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	return s.m.Hash()
+}
+
+// lenientSlice is the ISlice of a normalizing set. Its numeric conversions are the coercing As ones,
+// so Int() accepts the int64, uint64 and float64 elements the set stores as long as their value
+// converts exactly, and reports the others with a *ConversionError.
+type lenientSlice struct {
+	Slice
+}
+
+// exactInt64 converts a numeric value to int64 if it can without loss.
+func exactInt64(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), true
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint, uint8, uint16, uint32, uint64:
+		u, _ := exactUint64(x)
+		return int64(u), u <= math.MaxInt64
+	case float32:
+		return exactInt64(float64(x))
+	case float64:
+		if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(x), true
+	case complex64:
+		return exactInt64(complex128(x))
+	case complex128:
+		if imag(x) != 0 {
+			return 0, false
+		}
+		return exactInt64(real(x))
+	}
+	return 0, false
+}
+
+// exactUint64 converts a numeric value to uint64 if it can without loss.
+func exactUint64(v interface{}) (uint64, bool) {
+	switch x := v.(type) {
+	case uint:
+		return uint64(x), true
+	case uint8:
+		return uint64(x), true
+	case uint16:
+		return uint64(x), true
+	case uint32:
+		return uint64(x), true
+	case uint64:
+		return x, true
+	case float32:
+		return exactUint64(float64(x))
+	case float64:
+		if x != math.Trunc(x) || x < 0 || x >= math.MaxUint64 {
+			return 0, false
+		}
+		return uint64(x), true
+	case complex64, complex128:
+		f, ok := exactFloat64(x)
+		if !ok {
+			return 0, false
+		}
+		return exactUint64(f)
+	}
+	i, ok := exactInt64(v)
+	return uint64(i), ok && i >= 0
+}
+
+// exactFloat64 converts a numeric value to float64 if it can without loss.
+func exactFloat64(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	case complex64:
+		return float64(real(x)), imag(x) == 0
+	case complex128:
+		return real(x), imag(x) == 0
+	case uint, uint64:
+		u, _ := exactUint64(x)
+		f := float64(u)
+		return f, f < math.MaxUint64 && uint64(f) == u
+	}
+	i, ok := exactInt64(v)
+	if !ok {
+		return 0, false
+	}
+	f := float64(i)
+	return f, f < math.MaxInt64 && int64(f) == i
+}
+
+func (s lenientSlice) Int() ([]int, error)         { return s.AsInt() }
+func (s lenientSlice) Int8() ([]int8, error)       { return s.AsInt8() }
+func (s lenientSlice) Int16() ([]int16, error)     { return s.AsInt16() }
+func (s lenientSlice) Int32() ([]int32, error)     { return s.AsInt32() }
+func (s lenientSlice) Int64() ([]int64, error)     { return s.AsInt64() }
+func (s lenientSlice) Uint() ([]uint, error)       { return s.AsUint() }
+func (s lenientSlice) Uint8() ([]uint8, error)     { return s.AsUint8() }
+func (s lenientSlice) Uint16() ([]uint16, error)   { return s.AsUint16() }
+func (s lenientSlice) Uint32() ([]uint32, error)   { return s.AsUint32() }
+func (s lenientSlice) Uint64() ([]uint64, error)   { return s.AsUint64() }
+func (s lenientSlice) Float32() ([]float32, error) { return s.AsFloat32() }
+func (s lenientSlice) Float64() ([]float64, error) { return s.AsFloat64() }
@@ -0,0 +1,147 @@
+package set
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNewNormalizingSet(t *testing.T) {
+	s, err := NewNormalizingSet(NumberRules{}, int64(1), uint8(2), 3.0, float32(4.5), math.NaN(), uint64(math.MaxUint64), "x")
+	if err != nil {
+		t.Fatalf("NewNormalizingSet() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		elem interface{}
+		want bool
+	}{
+		{name: "int", elem: 1, want: true},
+		{name: "float64", elem: 2.0, want: true},
+		{name: "int32", elem: int32(3), want: true},
+		{name: "complex", elem: complex(3, 0), want: true},
+		{name: "float", elem: 4.5, want: true},
+		{name: "NaN", elem: float32(math.NaN()), want: true},
+		{name: "uint64", elem: float64(math.MaxUint64), want: false},
+		{name: "large uint64", elem: uint(math.MaxUint64), want: true},
+		{name: "string", elem: "x", want: true},
+		{name: "fraction", elem: 1.5, want: false},
+		{name: "other string", elem: "1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Contains(tt.elem); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.elem, got, tt.want)
+			}
+		})
+	}
+	if s.Adds(1.0, math.NaN()) || s.Cardinality() != 7 {
+		t.Errorf("Adds() = %v, want equal numbers deduplicated", s)
+	}
+	if !s.Removes(int16(1), math.NaN()) || s.Contains(1) {
+		t.Errorf("Removes() = %v", s)
+	}
+	other, _ := NewNormalizingSet(NumberRules{}, 2, 3, 4)
+	if got, want := s.Intersections(other), NewSet(int64(2), int64(3)); !got.Equal(want) {
+		t.Errorf("Intersections() = %v, want %v", got, want)
+	}
+	if got := s.Complements(other); got.Cardinality() != 3 || !got.Contains("x") {
+		t.Errorf("Complements() = %v", got)
+	}
+	if got := s.Unions(other); got.Cardinality() != 6 || !other.IsSub(got) {
+		t.Errorf("Unions() = %v", got)
+	}
+}
+
+func TestNumberRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules NumberRules
+		elems []interface{}
+		err   bool
+		card  int
+	}{
+		{name: "floats are integers", elems: []interface{}{1, 1.0, float32(1)}, card: 1},
+		{name: "keep floats", rules: NumberRules{KeepFloats: true}, elems: []interface{}{1, 1.0, float32(1)}, card: 2},
+		{name: "zeros", elems: []interface{}{0, 0.0, math.Copysign(0, -1)}, card: 1},
+		{name: "NaNs", elems: []interface{}{math.NaN(), math.NaN()}, card: 1},
+		{name: "reject NaN", rules: NumberRules{NaN: NaNReject}, elems: []interface{}{1, math.NaN()}, err: true},
+		{name: "overflow", elems: []interface{}{1e30, 1e30}, card: 1},
+		{name: "reject overflow", rules: NumberRules{Overflow: OverflowReject}, elems: []interface{}{1e30}, err: true},
+		{name: "infinity", rules: NumberRules{Overflow: OverflowReject}, elems: []interface{}{math.Inf(1)}, card: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewNormalizingSet(tt.rules, tt.elems...)
+			var ne *NumberError
+			if tt.err {
+				if !errors.As(err, &ne) || ne.Index != len(tt.elems)-1 || !errors.Is(err, ErrRejectedNumber) {
+					t.Errorf("NewNormalizingSet() error = %v, want a *NumberError", err)
+				}
+				return
+			}
+			if err != nil || s.Cardinality() != tt.card {
+				t.Errorf("NewNormalizingSet() = %v, %v, want %v elements", s, err, tt.card)
+			}
+		})
+	}
+	s, _ := NewNormalizingSet(NumberRules{NaN: NaNReject})
+	if ok, err := TryAdds(s, 1, math.NaN()); ok || err == nil || err.Error() != "go-set: element 1, NaN is rejected" || s.Contains(1) {
+		t.Errorf("TryAdds() = %v, %v, want an error", ok, err)
+	}
+	if s.Contains(math.NaN()) {
+		t.Errorf("Contains(NaN) = true, want false")
+	}
+	expectPanic(t, "Adds()", ErrRejectedNumber, func() { s.Adds(math.NaN()) })
+}
+
+func TestLenientSlice(t *testing.T) {
+	s := lenientSlice{Slice{int64(1), uint8(2), 3.0, float32(4), complex(5, 0)}}
+	if got, err := s.Int(); err != nil || !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Int() = %v, %v", got, err)
+	}
+	if got, err := s.Uint8(); err != nil || !reflect.DeepEqual(got, []uint8{1, 2, 3, 4, 5}) {
+		t.Errorf("Uint8() = %v, %v", got, err)
+	}
+	if got, err := s.Float32(); err != nil || !reflect.DeepEqual(got, []float32{1, 2, 3, 4, 5}) {
+		t.Errorf("Float32() = %v, %v", got, err)
+	}
+	tests := []struct {
+		name string
+		fn   func() (interface{}, error)
+	}{
+		{name: "Int of a fraction", fn: func() (interface{}, error) { return lenientSlice{Slice{1.5}}.Int() }},
+		{name: "Int8 overflow", fn: func() (interface{}, error) { return lenientSlice{Slice{128}}.Int8() }},
+		{name: "Int64 of a large uint64", fn: func() (interface{}, error) { return lenientSlice{Slice{uint64(math.MaxUint64)}}.Int64() }},
+		{name: "Uint of a negative", fn: func() (interface{}, error) { return lenientSlice{Slice{-1}}.Uint() }},
+		{name: "Float64 of an inexact int64", fn: func() (interface{}, error) { return lenientSlice{Slice{int64(1<<53 + 1)}}.Float64() }},
+		{name: "Float32 of an inexact float64", fn: func() (interface{}, error) { return lenientSlice{Slice{0.1}}.Float32() }},
+		{name: "Int of a complex", fn: func() (interface{}, error) { return lenientSlice{Slice{complex(1, 1)}}.Int() }},
+		{name: "Int of a string", fn: func() (interface{}, error) { return lenientSlice{Slice{"1"}}.Int() }},
+		{name: "String of an int", fn: func() (interface{}, error) { return lenientSlice{Slice{1}}.String() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.fn(); err == nil {
+				t.Errorf("got %v, want an error", got)
+			}
+		})
+	}
+	var ce *ConversionError
+	if _, err := (lenientSlice{Slice{1, 1.5}}).Int(); !errors.As(err, &ce) || len(ce.Invalid) != 1 {
+		t.Errorf("Int() error = %v, want a *ConversionError for %v", err, 1.5)
+	}
+	n, _ := NewNormalizingSet(NumberRules{}, 1, 2.0, int8(3))
+	got, err := n.ToSlice().Int()
+	sort.Ints(got)
+	if err != nil || !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("ToSlice().Int() = %v, %v, want %v", got, err, []int{1, 2, 3})
+	}
+	got64, err := n.ToSlice().Int64()
+	sort.Slice(got64, func(i, j int) bool { return got64[i] < got64[j] })
+	if err != nil || !reflect.DeepEqual(got64, []int64{1, 2, 3}) {
+		t.Errorf("ToSlice().Int64() = %v, %v, want %v", got64, err, []int64{1, 2, 3})
+	}
+}
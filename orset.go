@@ -0,0 +1,400 @@
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// dot uniquely tags one addition: the replica that made it and that replica's sequence number.
+type dot struct {
+	replica string
+	seq     uint64
+}
+
+type dots map[dot]struct{}
+
+// causalContext is the set of dots a replica has observed, compressed as a version vector
+// plus the dots that are not yet contiguous with it.
+type causalContext struct {
+	vv    map[string]uint64
+	cloud dots
+}
+
+func newCausalContext() causalContext {
+	return causalContext{vv: make(map[string]uint64), cloud: make(dots)}
+}
+
+func (c *causalContext) contains(d dot) bool {
+	if d.seq <= c.vv[d.replica] {
+		return true
+	}
+	_, ok := c.cloud[d]
+	return ok
+}
+
+func (c *causalContext) add(d dot) {
+	if !c.contains(d) {
+		c.cloud[d] = struct{}{}
+	}
+}
+
+func (c *causalContext) merge(other causalContext) {
+	for replica, seq := range other.vv {
+		if seq > c.vv[replica] {
+			c.vv[replica] = seq
+		}
+	}
+	for d := range other.cloud {
+		c.add(d)
+	}
+	c.compact()
+}
+
+// compact folds the dots contiguous with the version vector into it, and drops the ones it covers.
+func (c *causalContext) compact() {
+	for changed := true; changed; {
+		changed = false
+		for d := range c.cloud {
+			switch {
+			case d.seq <= c.vv[d.replica]:
+				delete(c.cloud, d)
+			case d.seq == c.vv[d.replica]+1:
+				c.vv[d.replica] = d.seq
+				delete(c.cloud, d)
+				changed = true
+			}
+		}
+	}
+}
+
+func (c causalContext) clone() causalContext {
+	result := newCausalContext()
+	for replica, seq := range c.vv {
+		result.vv[replica] = seq
+	}
+	for d := range c.cloud {
+		result.cloud[d] = struct{}{}
+	}
+	return result
+}
+
+func (c causalContext) equal(other causalContext) bool {
+	for replica, seq := range c.vv {
+		if other.vv[replica] != seq {
+			return false
+		}
+	}
+	for replica, seq := range other.vv {
+		if c.vv[replica] != seq {
+			return false
+		}
+	}
+	if len(c.cloud) != len(other.cloud) {
+		return false
+	}
+	for d := range c.cloud {
+		if _, ok := other.cloud[d]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// NewORSet returns an empty observed-remove set CRDT replica. replica must be unique among the
+// replicas that will ever merge with each other, it names the tags of the additions made here.
+func NewORSet(replica string) *ORSet {
+	return &ORSet{replica: replica, entries: make(map[interface{}]dots), ctx: newCausalContext()}
+}
+
+// ORSet is an add-wins observed-remove set (OR-Set) CRDT with delta-state synchronization.
+//
+// Every addition is tagged with a unique dot, and a removal only removes the tags its replica has observed,
+// so an element can be removed and added again, and an addition concurrent with a removal wins.
+// Removed tags do not linger as per-element tombstones: each replica keeps a causal context of the tags
+// it has observed, which Merge compacts into one counter per replica, so the metadata of removed
+// elements is garbage collected as soon as the context is contiguous.
+//
+// Replicas can exchange full states with Merge, or, more cheaply, the delta produced by Delta,
+// which only describes the mutations made since the previous call. It is not thread safe.
+type ORSet struct {
+	replica string
+	entries map[interface{}]dots
+	ctx     causalContext
+	delta   *ORSet // mutations not yet returned by Delta
+}
+
+// Add adds elements to the replica. Returns whether all the items was added,
+// elements already present are re-tagged.
+func (s *ORSet) Add(elems ...interface{}) bool {
+	var exist bool
+	for i := 0; i < len(elems); i++ {
+		if s.Contains(elems[i]) {
+			exist = true
+		}
+		d := dot{replica: s.replica, seq: s.ctx.vv[s.replica] + 1}
+		delta := NewORSet(s.replica)
+		delta.entries[elems[i]] = dots{d: struct{}{}}
+		delta.ctx.add(d)
+		for old := range s.entries[elems[i]] {
+			delta.ctx.add(old)
+		}
+		delta.ctx.compact()
+		s.apply(delta)
+	}
+	return !exist
+}
+
+// Remove removes elements from the replica. Returns whether all the items was removed.
+func (s *ORSet) Remove(elems ...interface{}) bool {
+	var notExist bool
+	for i := 0; i < len(elems); i++ {
+		if !s.Contains(elems[i]) {
+			notExist = true
+			continue
+		}
+		delta := NewORSet(s.replica)
+		for old := range s.entries[elems[i]] {
+			delta.ctx.add(old)
+		}
+		delta.ctx.compact()
+		s.apply(delta)
+	}
+	return !notExist
+}
+
+// apply joins a local mutation into the state and into the pending delta.
+func (s *ORSet) apply(delta *ORSet) {
+	s.join(delta)
+	if s.delta == nil {
+		s.delta = NewORSet(s.replica)
+	}
+	s.delta.join(delta)
+}
+
+// join merges the state of t into s, this is the CRDT join of add-wins sets.
+// A tag survives if both sides have it, or if the side missing it has never observed it.
+func (s *ORSet) join(t *ORSet) {
+	for elem, ds := range s.entries {
+		tds := t.entries[elem]
+		for d := range ds {
+			if _, ok := tds[d]; !ok && t.ctx.contains(d) {
+				delete(ds, d)
+			}
+		}
+		if len(ds) == 0 {
+			delete(s.entries, elem)
+		}
+	}
+	for elem, tds := range t.entries {
+		for d := range tds {
+			if s.ctx.contains(d) {
+				continue
+			}
+			ds, ok := s.entries[elem]
+			if !ok {
+				ds = make(dots)
+				s.entries[elem] = ds
+			}
+			ds[d] = struct{}{}
+		}
+	}
+	s.ctx.merge(t.ctx)
+}
+
+// Merge folds the state of another replica, or a delta produced by its Delta method, into s.
+// Merge is commutative, associative and idempotent, so states and deltas can be delivered
+// in any order and more than once.
+func (s *ORSet) Merge(other *ORSet) {
+	s.join(other)
+}
+
+// Delta returns the mutations made on this replica since the previous call, as a state that peers Merge,
+// or nil if there were none. Deltas are small and, unlike full states, cheap to ship on every change;
+// a peer that may have missed some should be sent the full state instead.
+func (s *ORSet) Delta() *ORSet {
+	delta := s.delta
+	s.delta = nil
+	return delta
+}
+
+// Contains returns whether the given items are all in the set.
+func (s *ORSet) Contains(elems ...interface{}) bool {
+	for i := 0; i < len(elems); i++ {
+		if _, ok := s.entries[elems[i]]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Cardinality returns the number of elements in the set.
+func (s *ORSet) Cardinality() int {
+	return len(s.entries)
+}
+
+// Value returns a copy of the current elements, see NewThreadUnsafeSet.
+func (s *ORSet) Value() ISet {
+	result := make(threadUnsafeSet, len(s.entries))
+	for elem := range s.entries {
+		result[elem] = struct{}{}
+	}
+	return &result
+}
+
+// Clone returns an independent copy of the replica, with the same replica name and no pending delta.
+func (s *ORSet) Clone() *ORSet {
+	result := NewORSet(s.replica)
+	for elem, ds := range s.entries {
+		cds := make(dots, len(ds))
+		for d := range ds {
+			cds[d] = struct{}{}
+		}
+		result.entries[elem] = cds
+	}
+	result.ctx = s.ctx.clone()
+	return result
+}
+
+// Equal returns whether two replicas have the same state, tags and causal context included.
+func (s *ORSet) Equal(other *ORSet) bool {
+	if len(s.entries) != len(other.entries) || !s.ctx.equal(other.ctx) {
+		return false
+	}
+	for elem, ds := range s.entries {
+		ods, ok := other.entries[elem]
+		if !ok || len(ods) != len(ds) {
+			return false
+		}
+		for d := range ds {
+			if _, ok := ods[d]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *ORSet) String() string {
+	return s.Value().String()
+}
+
+// MarshalBinary encodes the replica state, see WriteSet for the supported element types.
+// The pending delta is not encoded.
+func (s *ORSet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	writeString(&buf, s.replica)
+	buf.Write(appendUvarint(nil, uint64(len(s.entries))))
+	for elem, ds := range s.entries {
+		if err := writeElem(&buf, elem, nil); err != nil {
+			return nil, err
+		}
+		writeDots(&buf, ds)
+	}
+	buf.Write(appendUvarint(nil, uint64(len(s.ctx.vv))))
+	for replica, seq := range s.ctx.vv {
+		writeString(&buf, replica)
+		buf.Write(appendUvarint(nil, seq))
+	}
+	writeDots(&buf, s.ctx.cloud)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the replica state, and name, with one encoded by MarshalBinary.
+func (s *ORSet) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	result := NewORSet("")
+	var err error
+	if result.replica, err = readString(r); err != nil {
+		return orSetDecodeErr(err)
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return orSetDecodeErr(err)
+	}
+	for i := uint64(0); i < n; i++ {
+		elem, _, err := readElem(r, nil)
+		if err != nil {
+			return orSetDecodeErr(err)
+		}
+		ds, err := readDots(r)
+		if err != nil {
+			return orSetDecodeErr(err)
+		}
+		result.entries[elem] = ds
+	}
+	if n, err = binary.ReadUvarint(r); err != nil {
+		return orSetDecodeErr(err)
+	}
+	for i := uint64(0); i < n; i++ {
+		replica, err := readString(r)
+		if err != nil {
+			return orSetDecodeErr(err)
+		}
+		if result.ctx.vv[replica], err = binary.ReadUvarint(r); err != nil {
+			return orSetDecodeErr(err)
+		}
+	}
+	if result.ctx.cloud, err = readDots(r); err != nil {
+		return orSetDecodeErr(err)
+	}
+	if r.Len() != 0 {
+		return orSetDecodeErr(errors.New("trailing bytes"))
+	}
+	*s = *result
+	return nil
+}
+
+func orSetDecodeErr(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("go-set: ORSet UnmarshalBinary() err, %v", err)
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.Write(appendUvarint(nil, uint64(len(s))))
+	buf.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if l > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	b := make([]byte, l)
+	_, err = io.ReadFull(r, b)
+	return string(b), err
+}
+
+func writeDots(buf *bytes.Buffer, ds dots) {
+	buf.Write(appendUvarint(nil, uint64(len(ds))))
+	for d := range ds {
+		writeString(buf, d.replica)
+		buf.Write(appendUvarint(nil, d.seq))
+	}
+}
+
+func readDots(r *bytes.Reader) (dots, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	ds := make(dots)
+	for i := uint64(0); i < n; i++ {
+		var d dot
+		if d.replica, err = readString(r); err != nil {
+			return nil, err
+		}
+		if d.seq, err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+		ds[d] = struct{}{}
+	}
+	return ds, nil
+}
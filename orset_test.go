@@ -0,0 +1,128 @@
+package set
+
+import (
+	"math/rand"
+	"testing"
+)
+
+var orSetReplicas = []string{"a", "b", "c"}
+
+// randomORSet returns a replica that went through random operations, merging now and then with random peers.
+func randomORSet(r *rand.Rand) *ORSet {
+	s := NewORSet(orSetReplicas[r.Intn(len(orSetReplicas))])
+	for i := r.Intn(15); i > 0; i-- {
+		switch r.Intn(4) {
+		case 0:
+			s.Remove(r.Intn(10))
+		case 1:
+			peer := NewORSet(orSetReplicas[r.Intn(len(orSetReplicas))] + "'")
+			peer.Add(r.Intn(10))
+			s.Merge(peer)
+		default:
+			s.Add(r.Intn(10))
+		}
+	}
+	return s
+}
+
+func mergedORSet(a, b *ORSet) *ORSet {
+	result := a.Clone()
+	result.Merge(b)
+	return result
+}
+
+func TestORSet_MergeProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < crdtPropertyRounds; i++ {
+		a, b, c := randomORSet(r), randomORSet(r), randomORSet(r)
+		if !mergedORSet(a, b).Equal(mergedORSet(b, a)) {
+			t.Fatalf("Merge() not commutative for %v, %v", a, b)
+		}
+		if !mergedORSet(mergedORSet(a, b), c).Equal(mergedORSet(a, mergedORSet(b, c))) {
+			t.Fatalf("Merge() not associative for %v, %v, %v", a, b, c)
+		}
+		if !mergedORSet(a, a).Equal(a) || !mergedORSet(mergedORSet(a, b), b).Equal(mergedORSet(a, b)) {
+			t.Fatalf("Merge() not idempotent for %v, %v", a, b)
+		}
+	}
+}
+
+func TestORSet_Delta(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	replicas := make([]*ORSet, len(orSetReplicas))
+	for i, name := range orSetReplicas {
+		replicas[i] = NewORSet(name)
+	}
+	var deltas []*ORSet
+	for i := 0; i < 500; i++ {
+		s := replicas[r.Intn(len(replicas))]
+		if r.Intn(3) == 0 {
+			s.Remove(r.Intn(20))
+		} else {
+			s.Add(r.Intn(20))
+		}
+		if d := s.Delta(); d != nil {
+			deltas = append(deltas, d)
+		}
+		if len(deltas) > 0 && r.Intn(10) == 0 {
+			// Deliver a random delta to a random peer, possibly again and out of order.
+			replicas[r.Intn(len(replicas))].Merge(deltas[r.Intn(len(deltas))])
+		}
+	}
+	for _, s := range replicas {
+		for _, i := range r.Perm(len(deltas)) {
+			s.Merge(deltas[i])
+		}
+	}
+	for _, s := range replicas[1:] {
+		if !s.Equal(replicas[0]) {
+			t.Fatalf("replicas did not converge: %v, %v", s, replicas[0])
+		}
+	}
+	for _, s := range replicas {
+		if n := len(s.ctx.cloud); n != 0 {
+			t.Errorf("causal context keeps %v dots after compaction, want 0", n)
+		}
+	}
+}
+
+func TestORSet(t *testing.T) {
+	a, b := NewORSet("a"), NewORSet("b")
+	if !a.Add(1, 2, 3) || a.Add(1) || !a.Remove(3) || a.Remove(3) {
+		t.Errorf("Add(), Remove() = %v, want %v", a, "{1,2}")
+	}
+	b.Merge(a)
+	// Concurrently, a removes 1 and b re-adds it: the addition wins.
+	a.Remove(1)
+	b.Add(1)
+	b.Remove(2)
+	a.Merge(b.Delta())
+	if want := NewSet(1); !a.Value().Equal(want) || a.Cardinality() != 1 || !a.Contains(1) || a.Contains(2) {
+		t.Errorf("Merge() = %v, want %v", a, want)
+	}
+	// An element removed and added again on the same replica is back.
+	a.Remove(1)
+	a.Add(1)
+	b.Merge(a)
+	if want := NewSet(1); !b.Value().Equal(want) || b.String() != want.String() {
+		t.Errorf("Merge() = %v, want %v", b, want)
+	}
+	if a.Delta() == nil || a.Delta() != nil {
+		t.Errorf("Delta() should be reset by each call")
+	}
+
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	got := NewORSet("")
+	if err := got.UnmarshalBinary(data); err != nil || !got.Equal(a) || got.replica != "a" {
+		t.Errorf("UnmarshalBinary() = %v, %v, want %v", got, err, a)
+	}
+	if err := got.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Errorf("UnmarshalBinary() error = %v, want an error", err)
+	}
+	if _, err := NewORSet("a").Clone().MarshalBinary(); err != nil {
+		t.Errorf("MarshalBinary() error = %v", err)
+	}
+}
@@ -0,0 +1,372 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrSyntax is wrapped by the errors reporting malformed set notation, see Parse.
+var ErrSyntax = errors.New("go-set: invalid set notation")
+
+// maxParseDepth bounds the nesting of sets in Parse, so hostile input cannot exhaust the stack.
+const maxParseDepth = 64
+
+// SyntaxError reports malformed set notation.
+type SyntaxError struct {
+	// Func is the function that read the input, such as Parse.
+	Func string
+	// Offset is the byte offset of the error in the input.
+	Offset int
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("go-set: %s() err, offset %d: %s", e.Func, e.Offset, e.Msg)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return ErrSyntax
+}
+
+// Parse reads a set in the notation printed by String, the inverse of String for elements of basic types.
+// Bare elements are read as nil (<nil>), bool, int (uint64 if too large), float64 (NaN and ±Inf included),
+// complex128 or, if they are none of those, string. Strings holding commas, braces, quotes or surrounding
+// spaces, or reading as another type, are double-quoted with Go escapes, as String prints them.
+// Nested sets are read as Frozen sets. Spaces around elements are ignored.
+// Malformed input returns a *SyntaxError, and so does output truncated with a precision, as in {1,2,... 1 more}.
+// Examples:
+// Parse(`{1,2.5,a,"b,c",{x}}`) // {1,2.5,a,"b,c",{x}}, nil
+// Parse("{1,2") // nil, go-set: Parse() err, offset 4: expected ',' or '}'
+func Parse(s string) (ISet, error) {
+	elems, err := parseSet(s, reflect.Invalid)
+	if err != nil {
+		return nil, err
+	}
+	return NewSet(elems...), nil
+}
+
+// ParseAs is like Parse, but reads every element as a value of kind k, which must be a bool, numeric or
+// string kind, and returns a set typed with NewSetOfKind. Quotes are optional for strings,
+// and elements that do not read as k return a *SyntaxError.
+// Examples:
+// ParseAs("{1,2}", reflect.Int8) // {1,2} of int8, nil
+// ParseAs("{1,2}", reflect.String) // {"1","2"}, nil
+func ParseAs(s string, k reflect.Kind) (ISet, error) {
+	if !parsableKind(k) {
+		return nil, fmt.Errorf("go-set: ParseAs() err, unsupported kind %v", k)
+	}
+	elems, err := parseSet(s, k)
+	if err != nil {
+		return nil, err
+	}
+	return NewSetOfKind(k, elems...)
+}
+
+func parsableKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+func parseSet(s string, k reflect.Kind) ([]interface{}, error) {
+	fn := "Parse"
+	if k != reflect.Invalid {
+		fn = "ParseAs"
+	}
+	p := &parser{fn: fn, s: s, kind: k}
+	elems, err := p.set(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q after the set", p.s[p.pos])
+	}
+	return elems, nil
+}
+
+// parser is a recursive descent parser of the set notation, reading elements as kind,
+// or guessing their type if kind is reflect.Invalid.
+type parser struct {
+	fn   string
+	s    string
+	pos  int
+	kind reflect.Kind
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return p.errorAt(p.pos, format, args...)
+}
+
+func (p *parser) errorAt(offset int, format string, args ...interface{}) error {
+	return &SyntaxError{Func: p.fn, Offset: offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *parser) set(depth int) ([]interface{}, error) {
+	if depth == maxParseDepth {
+		return nil, p.errorf("sets nested deeper than %d", maxParseDepth)
+	}
+	if p.skipSpace(); p.pos == len(p.s) || p.s[p.pos] != '{' {
+		return nil, p.errorf("expected '{'")
+	}
+	p.pos++
+	var elems []interface{}
+	if p.skipSpace(); p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return elems, nil
+	}
+	for {
+		elem, err := p.elem(depth)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+		if p.skipSpace(); p.pos == len(p.s) || (p.s[p.pos] != ',' && p.s[p.pos] != '}') {
+			return nil, p.errorf("expected ',' or '}'")
+		}
+		p.pos++
+		if p.s[p.pos-1] == '}' {
+			return elems, nil
+		}
+	}
+}
+
+func (p *parser) elem(depth int) (interface{}, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.pos == len(p.s) {
+		return nil, p.errorf("missing element")
+	}
+	switch p.s[p.pos] {
+	case '{':
+		if p.kind != reflect.Invalid {
+			return nil, p.errorf("nested set, want %v", p.kind)
+		}
+		elems, err := p.set(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		f, err := Freeze(NewThreadUnsafeSet(elems...))
+		if err != nil {
+			return nil, p.errorAt(start, "%v", err)
+		}
+		return f, nil
+	case '"':
+		return p.quoted()
+	}
+	for p.pos < len(p.s) && strings.IndexByte(",{}\"", p.s[p.pos]) < 0 {
+		p.pos++
+	}
+	tok := strings.TrimRight(p.s[start:p.pos], " \t\r\n")
+	if tok == "" {
+		return nil, p.errorAt(start, "missing element")
+	}
+	if truncationMarker(tok) {
+		return nil, p.errorAt(start, "truncated set, %q elements are missing", tok)
+	}
+	elem, ok := parseToken(tok, p.kind)
+	if !ok {
+		return nil, p.errorAt(start, "invalid %v %q", p.kind, tok)
+	}
+	return elem, nil
+}
+
+// quoted reads a double-quoted string with Go escapes.
+func (p *parser) quoted() (interface{}, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.s) && p.s[p.pos] != '"'; p.pos++ {
+		if p.s[p.pos] == '\\' {
+			p.pos++
+		}
+	}
+	if p.pos >= len(p.s) {
+		return nil, p.errorAt(start, "unterminated string")
+	}
+	p.pos++
+	str, err := strconv.Unquote(p.s[start:p.pos])
+	if err != nil {
+		return nil, p.errorAt(start, "invalid quoted string")
+	}
+	if p.kind != reflect.Invalid && p.kind != reflect.String {
+		return nil, p.errorAt(start, "quoted string, want %v", p.kind)
+	}
+	return str, nil
+}
+
+// parseToken reads a bare element as kind k, or guesses its type if k is reflect.Invalid.
+func parseToken(tok string, k reflect.Kind) (interface{}, bool) {
+	switch k {
+	case reflect.Invalid:
+		return guessToken(tok), true
+	case reflect.String:
+		return tok, true
+	case reflect.Bool:
+		b, err := strconv.ParseBool(tok)
+		return b, err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := kindBits(k)
+		x, err := strconv.ParseInt(tok, 10, bits)
+		if err != nil {
+			return nil, false
+		}
+		return reflect.ValueOf(x).Convert(kindTypes[k]).Interface(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := strconv.ParseUint(tok, 10, kindBits(k))
+		if err != nil {
+			return nil, false
+		}
+		return reflect.ValueOf(x).Convert(kindTypes[k]).Interface(), true
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(tok, kindBits(k))
+		if err != nil {
+			return nil, false
+		}
+		return reflect.ValueOf(x).Convert(kindTypes[k]).Interface(), true
+	case reflect.Complex64, reflect.Complex128:
+		x, err := strconv.ParseComplex(tok, kindBits(k))
+		if err != nil {
+			return nil, false
+		}
+		return reflect.ValueOf(x).Convert(kindTypes[k]).Interface(), true
+	}
+	return nil, false
+}
+
+// guessToken reads a bare element as the first of nil, bool, int, uint64, float64, complex128
+// and string it is valid for.
+func guessToken(tok string) interface{} {
+	switch tok {
+	case "<nil>":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if x, err := strconv.ParseInt(tok, 10, strconv.IntSize); err == nil {
+		return int(x)
+	}
+	if x, err := strconv.ParseInt(tok, 10, 64); err == nil {
+		return x
+	}
+	if x, err := strconv.ParseUint(tok, 10, 64); err == nil {
+		return x
+	}
+	if x, err := strconv.ParseFloat(tok, 64); err == nil {
+		return x
+	}
+	if strings.HasPrefix(tok, "(") {
+		if x, err := strconv.ParseComplex(tok, 128); err == nil {
+			return x
+		}
+	}
+	return tok
+}
+
+var kindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Int: reflect.TypeOf(int(0)), reflect.Int8: reflect.TypeOf(int8(0)),
+	reflect.Int16: reflect.TypeOf(int16(0)), reflect.Int32: reflect.TypeOf(int32(0)),
+	reflect.Int64: reflect.TypeOf(int64(0)), reflect.Uint: reflect.TypeOf(uint(0)),
+	reflect.Uint8: reflect.TypeOf(uint8(0)), reflect.Uint16: reflect.TypeOf(uint16(0)),
+	reflect.Uint32: reflect.TypeOf(uint32(0)), reflect.Uint64: reflect.TypeOf(uint64(0)),
+	reflect.Uintptr: reflect.TypeOf(uintptr(0)), reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)), reflect.Complex64: reflect.TypeOf(complex64(0)),
+	reflect.Complex128: reflect.TypeOf(complex128(0)),
+}
+
+func kindBits(k reflect.Kind) int {
+	return int(kindTypes[k].Size()) * 8
+}
+
+// truncationMarker returns whether tok is the "... 9997 more" a precision truncates sets with.
+func truncationMarker(tok string) bool {
+	n := strings.TrimSuffix(strings.TrimPrefix(tok, "... "), " more")
+	if len(n) != len(tok)-len("...  more") {
+		return false
+	}
+	_, err := strconv.ParseUint(n, 10, 64)
+	return err == nil
+}
+
+// quoteElem quotes the strings Parse would not read back as themselves.
+func quoteElem(s string) string {
+	if s == "" || strings.ContainsAny(s, ",{}\"") || strings.TrimSpace(s) != s || guessToken(s) != s || truncationMarker(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// UnmarshalText replaces the elements of the set with the ones read by Parse.
+func (s *threadUnsafeSet) UnmarshalText(text []byte) error {
+	elems, err := parseSet(string(text), reflect.Invalid)
+	if err != nil {
+		return err
+	}
+	*s = *NewThreadUnsafeSet(elems...).(*threadUnsafeSet)
+	return nil
+}
+
+// UnmarshalText replaces the elements of the set with the ones read by Parse.
+func (s *threadSafeSet) UnmarshalText(text []byte) error {
+	elems, err := parseSet(string(text), reflect.Invalid)
+	if err != nil {
+		return err
+	}
+	m := NewThreadUnsafeSet(elems...).(*threadUnsafeSet)
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	s.m, s.shared = m, false
+	return nil
+}
+
+// UnmarshalText replaces the elements of the set with the ones read by ParseAs, for the kind of the set.
+// Elements of the wrong type return a *TypeError.
+func (s *typedSet) UnmarshalText(text []byte) error {
+	k := s.guard.kind
+	if s.guard.typ != nil {
+		k = s.guard.typ.Kind()
+	}
+	if !parsableKind(k) {
+		k = reflect.Invalid
+	}
+	elems, err := parseSet(string(text), k)
+	if err != nil {
+		return err
+	}
+	if err := s.guard.check(elems); err != nil {
+		return err
+	}
+	m := NewThreadUnsafeSet(elems...).(*threadUnsafeSet)
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	s.m, s.shared = m, false
+	return nil
+}
+
+// UnmarshalText replaces f with the set read by Parse.
+func (f *Frozen) UnmarshalText(text []byte) error {
+	elems, err := parseSet(string(text), reflect.Invalid)
+	if err != nil {
+		return err
+	}
+	result, err := Freeze(NewThreadUnsafeSet(elems...))
+	if err != nil {
+		return err
+	}
+	*f = result
+	return nil
+}
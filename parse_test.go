@@ -0,0 +1,145 @@
+package set
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ISet
+	}{
+		{"{}", NewSet()},
+		{" { } ", NewSet()},
+		{"{1,2,3}", NewSet(1, 2, 3)},
+		{"{ 1 , a b , -2.5 }", NewSet(1, "a b", -2.5)},
+		{"{true,<nil>,18446744073709551615,(1+2i)}", NewSet(true, nil, uint64(math.MaxUint64), complex(1, 2))},
+		{`{"a,b","{}","1","",x\y,"\"q\""}`, NewSet("a,b", "{}", "1", "", `x\y`, `"q"`)},
+		{"{1,{2,{}},{a}}", NewSet(1, NewFrozen(2, NewFrozen()), NewFrozen("a"))},
+		{"{1,1}", NewSet(1)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	tests := []struct {
+		in     string
+		offset int
+	}{
+		{"", 0},
+		{"1,2", 0},
+		{"{1,2", 4},
+		{"{1,,2}", 3},
+		{"{,}", 1},
+		{"{1}x", 3},
+		{`{"a}`, 1},
+		{`{"a"b}`, 4},
+		{`{"\z"}`, 1},
+		{"{a{b}}", 2},
+		{"{{1}", 4},
+		{"{1,2,... 1 more}", 5},
+		{"{... 3 more }", 1},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.in)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || !errors.Is(err, ErrSyntax) || syntaxErr.Offset != tt.offset {
+			t.Errorf("Parse(%q) error = %v, want a *SyntaxError at offset %d", tt.in, err, tt.offset)
+		}
+	}
+	deep := ""
+	for i := 0; i <= maxParseDepth; i++ {
+		deep = "{" + deep + "}"
+	}
+	if _, err := Parse(deep); !errors.Is(err, ErrSyntax) {
+		t.Errorf("Parse() of deeply nested sets error = %v, want %v", err, ErrSyntax)
+	}
+	if _, err := ParseAs(fmt.Sprintf("%.1v", NewSet("a", "b")), reflect.String); !errors.Is(err, ErrSyntax) {
+		t.Errorf("ParseAs() of a truncated set error = %v, want %v", err, ErrSyntax)
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	large := NewSet()
+	for i := 0; i < 1000; i++ {
+		large.Adds(i)
+	}
+	sets := []ISet{
+		NewSet(),
+		NewSet(1, -2, 3.5, "a", true, nil, math.Inf(1)),
+		NewSet("", " a", "a,b", "{", "}", `"`, "1", "true", "<nil>", "NaN", "(1+2i)", "x y"),
+		NewSet(NewFrozen(1, "a"), NewFrozen(), 2),
+		NewSet("... 1 more", "...", "... x more"),
+		large,
+	}
+	for _, s := range sets {
+		got, err := Parse(s.String())
+		if err != nil || !got.Equal(s) {
+			t.Errorf("Parse(%q) = %v, %v, want %v", s.String(), got, err, s)
+		}
+	}
+}
+
+func TestParseAs(t *testing.T) {
+	tests := []struct {
+		in   string
+		kind reflect.Kind
+		want ISet
+	}{
+		{"{1,2}", reflect.Int8, NewSet(int8(1), int8(2))},
+		{"{1,2}", reflect.String, NewSet("1", "2")},
+		{`{"a,b",c}`, reflect.String, NewSet("a,b", "c")},
+		{"{1.5,2}", reflect.Float32, NewSet(float32(1.5), float32(2))},
+		{"{true}", reflect.Bool, NewSet(true)},
+		{"{7}", reflect.Uint, NewSet(uint(7))},
+	}
+	for _, tt := range tests {
+		got, err := ParseAs(tt.in, tt.kind)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseAs(%q, %v) = %v, %v, want %v", tt.in, tt.kind, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"{300}", "{-1,a}", `{"1"}`, "{{1}}"} {
+		if _, err := ParseAs(in, reflect.Int8); !errors.Is(err, ErrSyntax) {
+			t.Errorf("ParseAs(%q, int8) error = %v, want %v", in, err, ErrSyntax)
+		}
+	}
+	if _, err := ParseAs("{}", reflect.Struct); err == nil {
+		t.Errorf("ParseAs() of kind struct error = %v, want an error", err)
+	}
+}
+
+func TestISet_UnmarshalText(t *testing.T) {
+	want := NewSet(1, "a")
+	typed, _ := NewSetOfKind(reflect.Int)
+	frozen := NewFrozen()
+	for _, s := range []interface{}{NewThreadUnsafeSet(9), NewSet(9), &frozen} {
+		if err := s.(encoding.TextUnmarshaler).UnmarshalText([]byte("{1,a}")); err != nil {
+			t.Fatalf("UnmarshalText() error = %v", err)
+		}
+		if !want.Equal(s.(ISet)) {
+			t.Errorf("UnmarshalText() = %v, want %v", s, want)
+		}
+	}
+	if err := typed.(encoding.TextUnmarshaler).UnmarshalText([]byte("{1,2}")); err != nil || !typed.Equal(NewSet(1, 2)) {
+		t.Errorf("typed UnmarshalText() = %v, %v, want %v", typed, err, "{1,2}")
+	}
+	if err := typed.(encoding.TextUnmarshaler).UnmarshalText([]byte("{a}")); !errors.Is(err, ErrSyntax) || !typed.Equal(NewSet(1, 2)) {
+		t.Errorf("typed UnmarshalText() = %v, %v, want %v", typed, err, ErrSyntax)
+	}
+	var config struct{ Tags *Frozen }
+	if err := json.Unmarshal([]byte(`{"Tags":"{a,b}"}`), &config); err != nil || !config.Tags.Equal(NewSet("a", "b")) {
+		t.Errorf("json.Unmarshal() = %v, %v, want %v", config.Tags, err, "{a,b}")
+	}
+}
@@ -0,0 +1,292 @@
+package set
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// Limits of the RESP requests and replies read. Allocation follows the data received, see respPrealloc.
+const (
+	maxRESPBulk  = 64 << 20
+	maxRESPArray = 1 << 20
+)
+
+// respPrealloc caps what is allocated up front for a declared length; buffers grow past it as the data arrives.
+const respPrealloc = 4 << 10
+
+func preallocRESP(n int) int {
+	if n > respPrealloc {
+		return respPrealloc
+	}
+	return n
+}
+
+// ReplyError is an error reply of a RESP server, such as "ERR unknown command 'FOO'".
+type ReplyError string
+
+func (e ReplyError) Error() string {
+	return string(e)
+}
+
+// respProtocolError reports malformed RESP, after which the stream cannot be resynchronized.
+type respProtocolError string
+
+func (e respProtocolError) Error() string {
+	return "Protocol error: " + string(e)
+}
+
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", respProtocolError("too big inline request")
+	}
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", respProtocolError("expected CRLF")
+	}
+	return string(line[:len(line)-2]), nil
+}
+
+// readRESPLength reads the length after the type byte of an array or a bulk string, -1 for null.
+func readRESPLength(line string, max int) (int, error) {
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < -1 || n > max {
+		return 0, respProtocolError(fmt.Sprintf("invalid length %q", line[1:]))
+	}
+	return n, nil
+}
+
+func readRESPBulk(r *bufio.Reader, n int) (string, error) {
+	var buf bytes.Buffer
+	buf.Grow(preallocRESP(n + 2))
+	if _, err := io.CopyN(&buf, r, int64(n+2)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	b := buf.Bytes()
+	if b[n] != '\r' || b[n+1] != '\n' {
+		return "", respProtocolError("expected CRLF after a bulk string")
+	}
+	return string(b[:n]), nil
+}
+
+// readRESPCommand reads a command, an array of bulk strings, or an inline command of space separated words.
+// It returns no argument for an empty command.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return splitInline(line)
+	}
+	n, err := readRESPLength(line, maxRESPArray)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, preallocRESP(n))
+	for i := 0; i < n; i++ {
+		line, err := readRESPLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, respProtocolError(fmt.Sprintf("expected '$', got %q", line))
+		}
+		l, err := readRESPLength(line, maxRESPBulk)
+		if err != nil || l < 0 {
+			return nil, respProtocolError(fmt.Sprintf("invalid bulk length %q", line[1:]))
+		}
+		arg, err := readRESPBulk(r, l)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// splitInline splits an inline command into words, which can be double-quoted with Go escapes.
+func splitInline(line string) ([]string, error) {
+	var args []string
+	for i := 0; i < len(line); {
+		switch {
+		case line[i] == ' ' || line[i] == '\t':
+			i++
+		case line[i] == '"':
+			p := &parser{s: line, pos: i}
+			arg, err := p.quoted()
+			if err != nil {
+				return nil, respProtocolError("unbalanced quotes in request")
+			}
+			args, i = append(args, arg.(string)), p.pos
+		default:
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			args = append(args, line[start:i])
+		}
+	}
+	return args, nil
+}
+
+// respWriter buffers RESP replies.
+type respWriter struct {
+	*bufio.Writer
+}
+
+func (w respWriter) simple(s string) {
+	w.WriteString("+" + s + "\r\n")
+}
+
+func (w respWriter) error(msg string) {
+	w.WriteString("-" + msg + "\r\n")
+}
+
+func (w respWriter) int(n int64) {
+	w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (w respWriter) bulk(s string) {
+	w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (w respWriter) null() {
+	w.WriteString("$-1\r\n")
+}
+
+func (w respWriter) array(n int) {
+	w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+func (w respWriter) bulks(elems []interface{}) {
+	w.array(len(elems))
+	for _, elem := range elems {
+		w.bulk(memberString(elem))
+	}
+}
+
+// readRESPReply reads a reply: a string for simple and bulk strings, a ReplyError, an int64,
+// a []interface{} for arrays, or nil for null bulk strings and arrays.
+func readRESPReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, respProtocolError("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return ReplyError(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, respProtocolError(fmt.Sprintf("invalid integer %q", line[1:]))
+		}
+		return n, nil
+	case '$':
+		n, err := readRESPLength(line, maxRESPBulk)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		return readRESPBulk(r, n)
+	case '*':
+		n, err := readRESPLength(line, maxRESPArray)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		elems := make([]interface{}, 0, preallocRESP(n))
+		for i := 0; i < n; i++ {
+			elem, err := readRESPReply(r)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+		return elems, nil
+	}
+	return nil, respProtocolError(fmt.Sprintf("unknown reply type %q", line[0]))
+}
+
+// Dial connects a RESP client to a server, such as one started by Server.Serve.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}, nil
+}
+
+// Client is a minimal RESP client. It is not thread safe.
+//
+// Do sends a command and waits for its reply. To pipeline commands, Send them,
+// Flush, then Receive their replies in order.
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// Do sends a command, such as Do("SADD", "key", "member"), and returns its reply, see Receive.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	if err := c.Send(args...); err != nil {
+		return nil, err
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+	return c.Receive()
+}
+
+// Send buffers a command, Flush sends the buffered commands.
+func (c *Client) Send(args ...string) error {
+	w := respWriter{c.w}
+	w.array(len(args))
+	for _, arg := range args {
+		w.bulk(arg)
+	}
+	return nil
+}
+
+// Flush sends the buffered commands.
+func (c *Client) Flush() error {
+	return c.w.Flush()
+}
+
+// Receive reads the reply to the oldest command sent: a string for simple and bulk strings, an int64,
+// a []interface{} for arrays, or nil for null replies. Error replies return a ReplyError.
+func (c *Client) Receive() (interface{}, error) {
+	reply, err := readRESPReply(c.r)
+	if err != nil {
+		var protocolErr respProtocolError
+		if errors.As(err, &protocolErr) {
+			return nil, fmt.Errorf("go-set: Client Receive() err, %v", err)
+		}
+		return nil, err
+	}
+	if e, ok := reply.(ReplyError); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
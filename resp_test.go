@@ -0,0 +1,100 @@
+package set
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_readRESPCommand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"*2\r\n$4\r\nSADD\r\n$3\r\na b\r\n", []string{"SADD", "a b"}},
+		{"*1\r\n$0\r\n\r\n", []string{""}},
+		{"*0\r\n", []string{}},
+		{"SADD key  member\r\n", []string{"SADD", "key", "member"}},
+		{`SADD key "a \"b\"" c` + "\r\n", []string{"SADD", "key", `a "b"`, "c"}},
+		{"\r\n", nil},
+	}
+	for _, tt := range tests {
+		got, err := readRESPCommand(bufio.NewReader(strings.NewReader(tt.in)))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readRESPCommand(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{
+		"*2\r\n$4\r\nSADD\r\n:1\r\n",
+		"*1\r\n$4\r\nSADDX\r\n",
+		"*x\r\n",
+		"*1\r\n$-1\r\n",
+		"*99999999\r\n",
+		"*1\r\n$999999999\r\n",
+		"SADD \"unterminated\r\n",
+		"SADD\n",
+		strings.Repeat("x", 5000) + "\r\n",
+	} {
+		var protocolErr respProtocolError
+		if _, err := readRESPCommand(bufio.NewReader(strings.NewReader(in))); !errors.As(err, &protocolErr) {
+			t.Errorf("readRESPCommand(%.20q) error = %v, want a protocol error", in, err)
+		}
+	}
+	if _, err := readRESPCommand(bufio.NewReader(strings.NewReader("*2\r\n$4\r\nSA"))); err != io.ErrUnexpectedEOF {
+		t.Errorf("readRESPCommand() of a truncated command error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func Test_readRESPCommand_DeclaredLengths(t *testing.T) {
+	// Declared lengths alone must not allocate: only the data read does.
+	for _, in := range []string{
+		"*1048576\r\n$4\r\nSADD\r\n",
+		"*1\r\n$67108864\r\nSADD",
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := readRESPCommand(bufio.NewReader(strings.NewReader(in))); err == nil {
+			t.Errorf("readRESPCommand(%.20q) error = %v, want an error", in, err)
+		}
+		runtime.ReadMemStats(&after)
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("readRESPCommand(%.20q) allocated %d bytes, want at most %d", in, n, 1<<20)
+		}
+	}
+	long := strings.Repeat("x", 3*respPrealloc)
+	got, err := readRESPCommand(bufio.NewReader(strings.NewReader("*1\r\n$12288\r\n" + long + "\r\n")))
+	if err != nil || len(got) != 1 || got[0] != long {
+		t.Errorf("readRESPCommand() of a long argument = %.20q, %v", got, err)
+	}
+}
+
+func Test_readRESPReply(t *testing.T) {
+	var b strings.Builder
+	w := respWriter{bufio.NewWriter(&b)}
+	w.simple("OK")
+	w.error("ERR boom")
+	w.int(-42)
+	w.bulk("a\r\nb")
+	w.null()
+	w.array(2)
+	w.int(1)
+	w.bulks([]interface{}{"x", 2})
+	w.Flush()
+	want := []interface{}{"OK", ReplyError("ERR boom"), int64(-42), "a\r\nb", nil, []interface{}{int64(1), []interface{}{"x", "2"}}}
+	r := bufio.NewReader(strings.NewReader(b.String()))
+	for _, w := range want {
+		if got, err := readRESPReply(r); err != nil || !reflect.DeepEqual(got, w) {
+			t.Errorf("readRESPReply() = %#v, %v, want %#v", got, err, w)
+		}
+	}
+	if _, err := readRESPReply(r); err != io.EOF {
+		t.Errorf("readRESPReply() at the end error = %v, want %v", err, io.EOF)
+	}
+	if _, err := readRESPReply(bufio.NewReader(strings.NewReader("?1\r\n"))); err == nil {
+		t.Errorf("readRESPReply() of an unknown type error = %v, want an error", err)
+	}
+}
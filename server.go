@@ -0,0 +1,438 @@
+package set
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Server.Serve after a call to Shutdown or Close.
+var ErrServerClosed = errors.New("go-set: Server closed")
+
+// NewServer returns a server exposing the sets of st over the Redis protocol (RESP), see Serve.
+func NewServer(st *Store) *Server {
+	return &Server{
+		store:     st,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Server serves the set commands of Redis over the Redis protocol (RESP2), so any Redis client can use a Store:
+// SADD, SREM, SISMEMBER, SMISMEMBER, SMEMBERS, SCARD, SUNION, SINTER, SDIFF, SUNIONSTORE, SINTERSTORE,
+// SDIFFSTORE, SMOVE, SPOP, SRANDMEMBER and SSCAN, along with DEL, EXISTS, KEYS, EXPIRE, PEXPIRE, PERSIST,
+// TTL, PTTL, PING, ECHO and QUIT. Members are strings.
+//
+// Clients may pipeline commands: replies are buffered, and flushed once every command received is answered.
+type Server struct {
+	store *Store
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup // the connections being served
+}
+
+// ListenAndServe listens on the TCP address addr, such as ":6379", and calls Serve.
+func (srv *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(l)
+}
+
+// Serve accepts connections on l and serves each of them in its own goroutine.
+// It closes l when it returns, and always returns an error, ErrServerClosed after Shutdown or Close.
+func (srv *Server) Serve(l net.Listener) error {
+	srv.mu.Lock()
+	if srv.closed {
+		srv.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	srv.listeners[l] = struct{}{}
+	srv.mu.Unlock()
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.listeners, l)
+		srv.mu.Unlock()
+		l.Close()
+	}()
+	var delay time.Duration
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			srv.mu.Lock()
+			closed := srv.closed
+			srv.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				// Back off like net/http, on errors such as running out of file descriptors.
+				if delay = 2 * delay; delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		srv.mu.Lock()
+		if srv.closed {
+			srv.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		srv.conns[conn] = struct{}{}
+		srv.wg.Add(1)
+		srv.mu.Unlock()
+		go srv.serveConn(conn)
+	}
+}
+
+// Shutdown gracefully stops the server: it closes the listeners, lets every connection finish the commands
+// it has received, answers them, and closes it. If ctx is done first, Shutdown closes the remaining
+// connections and returns the error of ctx.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.mu.Lock()
+	srv.closed = true
+	for l := range srv.listeners {
+		l.Close()
+	}
+	for conn := range srv.conns {
+		// Interrupt the connections waiting for a command, the busy ones stop after answering theirs.
+		conn.SetReadDeadline(time.Now())
+	}
+	srv.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		srv.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Close()
+		return ctx.Err()
+	}
+}
+
+// Close immediately closes the listeners and the connections.
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.closed = true
+	for l := range srv.listeners {
+		l.Close()
+	}
+	for conn := range srv.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+func (srv *Server) shuttingDown() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.closed
+}
+
+func (srv *Server) serveConn(conn net.Conn) {
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.conns, conn)
+		srv.mu.Unlock()
+		conn.Close()
+		srv.wg.Done()
+	}()
+	r := bufio.NewReader(conn)
+	w := respWriter{bufio.NewWriter(conn)}
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			var protocolErr respProtocolError
+			if errors.As(err, &protocolErr) {
+				w.error("ERR " + err.Error())
+			}
+			w.Flush()
+			return
+		}
+		quit := srv.exec(args, w)
+		// Flush once the pipelined commands already received are answered.
+		if r.Buffered() == 0 || quit {
+			if w.Flush() != nil || quit {
+				return
+			}
+			if srv.shuttingDown() {
+				return
+			}
+		}
+	}
+}
+
+type serverCommand struct {
+	// arity counts the arguments, the command name included, or their minimum if negative, as in Redis.
+	arity int
+	run   func(st *Store, args []string, w respWriter)
+}
+
+var serverCommands map[string]serverCommand
+
+func init() {
+	serverCommands = map[string]serverCommand{
+		"PING":        {-1, cmdPing},
+		"ECHO":        {2, func(st *Store, args []string, w respWriter) { w.bulk(args[1]) }},
+		"COMMAND":     {-1, func(st *Store, args []string, w respWriter) { w.array(0) }},
+		"SADD":        {-3, cmdSAdd},
+		"SREM":        {-3, cmdSRem},
+		"SISMEMBER":   {3, cmdSIsMember},
+		"SMISMEMBER":  {-3, cmdSMIsMember},
+		"SMEMBERS":    {2, func(st *Store, args []string, w respWriter) { w.bulks(sortedMembers(st.SMembers(args[1]))) }},
+		"SCARD":       {2, func(st *Store, args []string, w respWriter) { w.int(int64(st.SCard(args[1]))) }},
+		"SUNION":      {-2, func(st *Store, args []string, w respWriter) { w.bulks(sortedMembers(st.SUnion(args[1:]...))) }},
+		"SINTER":      {-2, func(st *Store, args []string, w respWriter) { w.bulks(sortedMembers(st.SInter(args[1:]...))) }},
+		"SDIFF":       {-2, func(st *Store, args []string, w respWriter) { w.bulks(sortedMembers(st.SDiff(args[1:]...))) }},
+		"SUNIONSTORE": {-3, func(st *Store, args []string, w respWriter) { w.int(int64(st.SUnionStore(args[1], args[2:]...))) }},
+		"SINTERSTORE": {-3, func(st *Store, args []string, w respWriter) { w.int(int64(st.SInterStore(args[1], args[2:]...))) }},
+		"SDIFFSTORE":  {-3, func(st *Store, args []string, w respWriter) { w.int(int64(st.SDiffStore(args[1], args[2:]...))) }},
+		"SMOVE":       {4, cmdSMove},
+		"SPOP":        {-2, cmdSPop},
+		"SRANDMEMBER": {-2, cmdSRandMember},
+		"SSCAN":       {-3, cmdSScan},
+		"DEL":         {-2, func(st *Store, args []string, w respWriter) { w.int(int64(st.Del(args[1:]...))) }},
+		"EXISTS":      {-2, func(st *Store, args []string, w respWriter) { w.int(int64(st.Exists(args[1:]...))) }},
+		"KEYS":        {2, cmdKeys},
+		"EXPIRE":      {3, cmdExpire},
+		"PEXPIRE":     {3, cmdExpire},
+		"PERSIST":     {2, func(st *Store, args []string, w respWriter) { w.int(boolInt(st.Persist(args[1]))) }},
+		"TTL":         {2, cmdTTL},
+		"PTTL":        {2, cmdTTL},
+	}
+}
+
+// exec runs a command and writes its reply, it returns whether the client quits.
+func (srv *Server) exec(args []string, w respWriter) (quit bool) {
+	if len(args) == 0 {
+		return false
+	}
+	name := strings.ToUpper(args[0])
+	if name == "QUIT" {
+		w.simple("OK")
+		return true
+	}
+	cmd, ok := serverCommands[name]
+	switch {
+	case !ok:
+		w.error("ERR unknown command '" + args[0] + "'")
+	case (cmd.arity > 0 && len(args) != cmd.arity) || len(args) < -cmd.arity:
+		w.error("ERR wrong number of arguments for '" + strings.ToLower(args[0]) + "' command")
+	default:
+		cmd.run(srv.store, args, w)
+	}
+	return false
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func stringArgs(args []string) []interface{} {
+	elems := make([]interface{}, len(args))
+	for i, arg := range args {
+		elems[i] = arg
+	}
+	return elems
+}
+
+func sortedMembers(s ISet) []interface{} {
+	elems := s.ToSlice().Interface()
+	sortElems(elems)
+	return elems
+}
+
+// intArg parses an integer argument, writing the error reply of Redis if it is not one.
+func intArg(arg string, w respWriter) (int64, bool) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		w.error("ERR value is not an integer or out of range")
+	}
+	return n, err == nil
+}
+
+func cmdPing(st *Store, args []string, w respWriter) {
+	switch len(args) {
+	case 1:
+		w.simple("PONG")
+	case 2:
+		w.bulk(args[1])
+	default:
+		w.error("ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+func cmdSAdd(st *Store, args []string, w respWriter) {
+	w.int(int64(st.SAdd(args[1], stringArgs(args[2:])...)))
+}
+
+func cmdSRem(st *Store, args []string, w respWriter) {
+	w.int(int64(st.SRem(args[1], stringArgs(args[2:])...)))
+}
+
+func cmdSIsMember(st *Store, args []string, w respWriter) {
+	w.int(boolInt(st.SIsMember(args[1], args[2])))
+}
+
+func cmdSMIsMember(st *Store, args []string, w respWriter) {
+	// One SMembers, so the answers are consistent with each other.
+	s := st.SMembers(args[1])
+	w.array(len(args) - 2)
+	for _, member := range args[2:] {
+		w.int(boolInt(s.Contains(member)))
+	}
+}
+
+func cmdSMove(st *Store, args []string, w respWriter) {
+	w.int(boolInt(st.SMove(args[1], args[2], args[3])))
+}
+
+// countArg parses the optional count of SPOP and SRANDMEMBER, which must not be negative if positive is set.
+func countArg(args []string, positive bool, w respWriter) (count int, given, ok bool) {
+	switch len(args) {
+	case 2:
+		return 0, false, true
+	case 3:
+		n, ok := intArg(args[2], w)
+		switch {
+		case !ok:
+			return 0, true, false
+		case positive && n < 0:
+			w.error("ERR value is out of range, must be positive")
+			return 0, true, false
+		case n > maxRESPArray || n < -maxRESPArray:
+			w.error("ERR value is out of range")
+			return 0, true, false
+		}
+		return int(n), true, true
+	}
+	w.error("ERR syntax error")
+	return 0, false, false
+}
+
+func cmdSPop(st *Store, args []string, w respWriter) {
+	count, given, ok := countArg(args, true, w)
+	switch {
+	case !ok:
+	case given:
+		w.bulks(st.SPopN(args[1], count))
+	default:
+		if member, ok := st.SPop(args[1]); ok {
+			w.bulk(memberString(member))
+		} else {
+			w.null()
+		}
+	}
+}
+
+func cmdSRandMember(st *Store, args []string, w respWriter) {
+	count, given, ok := countArg(args, false, w)
+	switch {
+	case !ok:
+	case given:
+		w.bulks(st.SRandMemberN(args[1], count))
+	default:
+		if member, ok := st.SRandMember(args[1]); ok {
+			w.bulk(memberString(member))
+		} else {
+			w.null()
+		}
+	}
+}
+
+func cmdSScan(st *Store, args []string, w respWriter) {
+	cursor, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		w.error("ERR invalid cursor")
+		return
+	}
+	var match string
+	var count int64
+	for i := 3; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			w.error("ERR syntax error")
+			return
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			match = args[i+1]
+		case "COUNT":
+			var ok bool
+			if count, ok = intArg(args[i+1], w); !ok {
+				return
+			}
+			if count < 1 || count > maxRESPArray {
+				w.error("ERR value is out of range")
+				return
+			}
+		default:
+			w.error("ERR syntax error")
+			return
+		}
+	}
+	next, members := st.SScan(args[1], cursor, match, int(count))
+	w.array(2)
+	w.bulk(strconv.FormatUint(next, 10))
+	w.bulks(members)
+}
+
+func cmdKeys(st *Store, args []string, w respWriter) {
+	keys := st.Keys(args[1])
+	w.array(len(keys))
+	for _, key := range keys {
+		w.bulk(key)
+	}
+}
+
+func cmdExpire(st *Store, args []string, w respWriter) {
+	n, ok := intArg(args[2], w)
+	if !ok {
+		return
+	}
+	unit := time.Second
+	if strings.EqualFold(args[0], "PEXPIRE") {
+		unit = time.Millisecond
+	}
+	if n > int64(1<<63-1)/int64(unit) || n < -int64(1<<63-1)/int64(unit) {
+		w.error("ERR invalid expire time in '" + strings.ToLower(args[0]) + "' command")
+		return
+	}
+	w.int(boolInt(st.Expire(args[1], time.Duration(n)*unit)))
+}
+
+func cmdTTL(st *Store, args []string, w respWriter) {
+	ttl, ok := st.TTL(args[1])
+	switch {
+	case !ok:
+		w.int(-2)
+	case ttl < 0:
+		w.int(-1)
+	case strings.EqualFold(args[0], "PTTL"):
+		w.int(int64((ttl + time.Millisecond/2) / time.Millisecond))
+	default:
+		w.int(int64((ttl + time.Second/2) / time.Second))
+	}
+}
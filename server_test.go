@@ -0,0 +1,251 @@
+package set
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer serves a new Store on a loopback listener, and stops the server when the test ends.
+func startServer(t *testing.T, now func() time.Time) (*Server, string, chan error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	srv := NewServer(NewStore(now))
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l) }()
+	t.Cleanup(func() { srv.Close() })
+	return srv, l.Addr().String(), served
+}
+
+func dialServer(t *testing.T, addr string) *Client {
+	c, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestServer(t *testing.T) {
+	now, set := fakeClock(0)
+	_, addr, _ := startServer(t, now)
+	c := dialServer(t, addr)
+	tests := []struct {
+		args []string
+		want interface{}
+	}{
+		{[]string{"PING"}, "PONG"},
+		{[]string{"ping", "hi"}, "hi"},
+		{[]string{"SADD", "a", "1", "2", "3", "3"}, int64(3)},
+		{[]string{"SADD", "b", "2", "3", "4"}, int64(3)},
+		{[]string{"SREM", "b", "4", "5"}, int64(1)},
+		{[]string{"SISMEMBER", "a", "1"}, int64(1)},
+		{[]string{"SMISMEMBER", "a", "1", "9"}, []interface{}{int64(1), int64(0)}},
+		{[]string{"SMEMBERS", "a"}, []interface{}{"1", "2", "3"}},
+		{[]string{"SMEMBERS", "missing"}, []interface{}{}},
+		{[]string{"SCARD", "a"}, int64(3)},
+		{[]string{"SUNION", "a", "b"}, []interface{}{"1", "2", "3"}},
+		{[]string{"SINTER", "a", "b"}, []interface{}{"2", "3"}},
+		{[]string{"SDIFF", "a", "b"}, []interface{}{"1"}},
+		{[]string{"SUNIONSTORE", "u", "a", "b"}, int64(3)},
+		{[]string{"SINTERSTORE", "i", "a", "b"}, int64(2)},
+		{[]string{"SDIFFSTORE", "d", "a", "b"}, int64(1)},
+		{[]string{"SMOVE", "a", "b", "1"}, int64(1)},
+		{[]string{"SMOVE", "a", "b", "1"}, int64(0)},
+		{[]string{"SPOP", "d"}, "1"},
+		{[]string{"SPOP", "d"}, nil},
+		{[]string{"SPOP", "missing", "5"}, []interface{}{}},
+		{[]string{"SRANDMEMBER", "i", "5"}, []interface{}{"2", "3"}},
+		{[]string{"SREM", "i", "2", "3"}, int64(2)},
+		{[]string{"SRANDMEMBER", "missing"}, nil},
+		{[]string{"SRANDMEMBER", "missing", "-2"}, []interface{}{}},
+		{[]string{"SSCAN", "b", "0", "MATCH", "[12]", "COUNT", "100"}, []interface{}{"0", []interface{}{"1", "2"}}},
+		{[]string{"KEYS", "*"}, []interface{}{"a", "b", "u"}},
+		{[]string{"EXISTS", "a", "b", "i"}, int64(2)},
+		{[]string{"EXPIRE", "u", "10"}, int64(1)},
+		{[]string{"TTL", "u"}, int64(10)},
+		{[]string{"PTTL", "u"}, int64(10000)},
+		{[]string{"TTL", "a"}, int64(-1)},
+		{[]string{"TTL", "missing"}, int64(-2)},
+		{[]string{"PERSIST", "u"}, int64(1)},
+		{[]string{"PEXPIRE", "u", "1500"}, int64(1)},
+		{[]string{"DEL", "a", "missing"}, int64(1)},
+		{[]string{"ECHO", "bye"}, "bye"},
+	}
+	for _, tt := range tests {
+		got, err := c.Do(tt.args...)
+		if err != nil {
+			t.Errorf("Do(%q) error = %v", tt.args, err)
+			continue
+		}
+		// The members of SRANDMEMBER and of SSCAN pages come in no particular order.
+		if members, ok := got.([]interface{}); ok && tt.args[0] == "SRANDMEMBER" {
+			sortElems(members)
+		}
+		if reply, ok := got.([]interface{}); ok && tt.args[0] == "SSCAN" {
+			sortElems(reply[1].([]interface{}))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Do(%q) = %#v, want %#v", tt.args, got, tt.want)
+		}
+	}
+	set(int64(2 * time.Second))
+	if got, err := c.Do("EXISTS", "u"); got != int64(0) || err != nil {
+		t.Errorf("Do(EXISTS) of an expired key = %v, %v, want %v", got, err, 0)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	_, addr, _ := startServer(t, nil)
+	c := dialServer(t, addr)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"NOPE"}, "ERR unknown command 'NOPE'"},
+		{[]string{"SADD", "a"}, "ERR wrong number of arguments for 'sadd' command"},
+		{[]string{"SISMEMBER", "a", "1", "2"}, "ERR wrong number of arguments for 'sismember' command"},
+		{[]string{"SPOP", "a", "x"}, "ERR value is not an integer or out of range"},
+		{[]string{"SPOP", "a", "-1"}, "ERR value is out of range, must be positive"},
+		{[]string{"SSCAN", "a", "x"}, "ERR invalid cursor"},
+		{[]string{"SSCAN", "a", "0", "COUNT"}, "ERR syntax error"},
+		{[]string{"SSCAN", "a", "0", "LIMIT", "1"}, "ERR syntax error"},
+		{[]string{"EXPIRE", "a", "99999999999999999"}, "ERR invalid expire time in 'expire' command"},
+	}
+	for _, tt := range tests {
+		_, err := c.Do(tt.args...)
+		var replyErr ReplyError
+		if !errors.As(err, &replyErr) || string(replyErr) != tt.want {
+			t.Errorf("Do(%q) error = %v, want %v", tt.args, err, tt.want)
+		}
+	}
+	if got, err := c.Do("PING"); got != "PONG" || err != nil {
+		t.Errorf("Do(PING) after errors = %v, %v, want %v", got, err, "PONG")
+	}
+}
+
+func TestServer_Inline(t *testing.T) {
+	_, addr, _ := startServer(t, nil)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "SADD k \"a b\" c\r\nSCARD k\r\nQUIT\r\n")
+	r := bufio.NewReader(conn)
+	for _, want := range []interface{}{int64(2), int64(2), "OK"} {
+		if got, err := readRESPReply(r); err != nil || got != want {
+			t.Errorf("readRESPReply() = %v, %v, want %v", got, err, want)
+		}
+	}
+	if _, err := r.ReadByte(); err == nil {
+		t.Errorf("connection still open after QUIT")
+	}
+}
+
+func TestServer_ProtocolError(t *testing.T) {
+	_, addr, _ := startServer(t, nil)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "*1\r\n:1\r\n")
+	r := bufio.NewReader(conn)
+	if got, err := readRESPReply(r); err != nil || !strings.HasPrefix(fmt.Sprint(got), "ERR Protocol error") {
+		t.Errorf("readRESPReply() = %v, %v, want a protocol error", got, err)
+	}
+	if _, err := r.ReadByte(); err == nil {
+		t.Errorf("connection still open after a protocol error")
+	}
+}
+
+func TestServer_Pipelining(t *testing.T) {
+	_, addr, _ := startServer(t, nil)
+	c := dialServer(t, addr)
+	const n = 5000
+	for i := 0; i < n; i++ {
+		c.Send("SADD", "p", fmt.Sprint(i%1000))
+	}
+	c.Send("SCARD", "p")
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	added := int64(0)
+	for i := 0; i < n; i++ {
+		got, err := c.Receive()
+		if err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+		added += got.(int64)
+	}
+	if got, err := c.Receive(); added != 1000 || got != int64(1000) || err != nil {
+		t.Errorf("Receive() = %v, %v after adding %d, want %v", got, err, added, 1000)
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	srv, addr, served := startServer(t, nil)
+	idle := dialServer(t, addr)
+	busy := dialServer(t, addr)
+	if _, err := idle.Do("PING"); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		busy.Send("SADD", "k", fmt.Sprint(i))
+	}
+	busy.Flush()
+	if got, err := busy.Receive(); got != int64(1) || err != nil {
+		t.Fatalf("Receive() = %v, %v, want %v", got, err, 1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Serve() error = %v, want %v", err, ErrServerClosed)
+	}
+	// The commands received before the shutdown are answered.
+	for i := 1; i < 100; i++ {
+		if got, err := busy.Receive(); got != int64(1) || err != nil {
+			t.Fatalf("Receive() of command %d = %v, %v, want %v", i, got, err, 1)
+		}
+	}
+	if _, err := idle.Do("PING"); err == nil {
+		t.Errorf("Do() after Shutdown() error = %v, want an error", err)
+	}
+	if _, err := Dial(addr); err == nil {
+		t.Errorf("Dial() after Shutdown() error = %v, want an error", err)
+	}
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	if err := srv.Serve(l); err != ErrServerClosed {
+		t.Errorf("Serve() after Shutdown() error = %v, want %v", err, ErrServerClosed)
+	}
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	srv, addr, _ := startServer(t, nil)
+	c := dialServer(t, addr)
+	c.Do("PING")
+	srv.mu.Lock()
+	srv.wg.Add(1) // a connection that never finishes
+	srv.mu.Unlock()
+	defer srv.wg.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := c.Do("PING"); err == nil {
+		t.Errorf("Do() after Shutdown() error = %v, want an error", err)
+	}
+}
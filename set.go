@@ -105,6 +105,13 @@ type ISet interface {
 	// NewMapSet(1,2,3).String()
 	// output: {1,2,3}
 	String() string
+	// Hash returns a fingerprint of the elements: equal sets have equal hashes, whatever their implementation
+	// and the order the elements were added in. Sets of strings, numbers, booleans and arrays or structs
+	// made of them, Frozen sets included, hash identically in every process. A set built by NewHashSet
+	// hashes like them only while its Hasher agrees with == on the elements.
+	// Examples:
+	// NewSet(1, 2).Hash() == NewThreadUnsafeSet(2, 1).Hash()
+	Hash() uint64
 }
 
 //
@@ -0,0 +1,176 @@
+package set
+
+import (
+	"fmt"
+)
+
+// NewShardedSet returns a thread safe set split into shards, each guarded by its own lock,
+// so goroutines working on different elements rarely contend.
+// shards is rounded up to a power of two, values below 1 mean 1.
+func NewShardedSet(shards int, elems ...interface{}) ISet {
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	s := &shardedSet{shards: make([]*threadSafeSet, n), mask: uint64(n - 1)}
+	for i := range s.shards {
+		s.shards[i] = NewSet().(*threadSafeSet)
+	}
+	s.Adds(elems...)
+	return s
+}
+
+type shardedSet struct {
+	shards []*threadSafeSet
+	mask   uint64
+}
+
+func (s *shardedSet) shard(elem interface{}) *threadSafeSet {
+	return s.shards[hashOf(elem)&s.mask]
+}
+
+func (s *shardedSet) Empty() bool {
+	return s.Cardinality() == 0
+}
+
+func (s *shardedSet) Singleton() bool {
+	return s.Cardinality() == 1
+}
+
+func (s *shardedSet) Cardinality() int {
+	var n int
+	for _, shard := range s.shards {
+		n += shard.Cardinality()
+	}
+	return n
+}
+
+func (s *shardedSet) ToSlice() ISlice {
+	result := make(Slice, 0, s.Cardinality())
+	for _, shard := range s.shards {
+		result = append(result, shard.ToSlice().(Slice)...)
+	}
+	return result
+}
+
+func (s *shardedSet) Adds(elems ...interface{}) bool {
+	var exist bool
+	for i := 0; i < len(elems); i++ {
+		if !s.shard(elems[i]).Adds(elems[i]) {
+			exist = true
+		}
+	}
+	return !exist
+}
+
+func (s *shardedSet) Removes(elems ...interface{}) bool {
+	var notExist bool
+	for i := 0; i < len(elems); i++ {
+		if !s.shard(elems[i]).Removes(elems[i]) {
+			notExist = true
+		}
+	}
+	return !notExist
+}
+
+func (s *shardedSet) IsSub(other ISet) bool {
+	if s.Cardinality() > other.Cardinality() {
+		return false
+	}
+	return other.Contains(s.ToSlice().Interface()...)
+}
+
+func (s *shardedSet) Unions(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Adds(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *shardedSet) Intersections(others ...ISet) ISet {
+	result := NewShardedSet(len(s.shards))
+	var baseSet ISet = s
+	var diffSets []ISet
+	for _, other := range others {
+		if other.Cardinality() < baseSet.Cardinality() {
+			diffSets = append(diffSets, baseSet)
+			baseSet = other
+		} else {
+			diffSets = append(diffSets, other)
+		}
+	}
+Loop:
+	for _, elem := range baseSet.ToSlice().Interface() {
+		for _, diffSet := range diffSets {
+			if !diffSet.Contains(elem) {
+				continue Loop
+			}
+		}
+		result.Adds(elem)
+	}
+	return result
+}
+
+func (s *shardedSet) Complements(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Removes(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *shardedSet) Clear() {
+	for _, shard := range s.shards {
+		shard.Clear()
+	}
+}
+
+func (s *shardedSet) Contains(elems ...interface{}) bool {
+	for i := 0; i < len(elems); i++ {
+		if !s.shard(elems[i]).Contains(elems[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *shardedSet) Clone() ISet {
+	return NewShardedSet(len(s.shards), s.ToSlice().Interface()...)
+}
+
+func (s *shardedSet) Equal(other ISet) bool {
+	if other.Cardinality() != s.Cardinality() {
+		return false
+	}
+	return s.Contains(other.ToSlice().Interface()...)
+}
+
+func (s *shardedSet) Pop() interface{} {
+	for _, shard := range s.shards {
+		if elem := shard.Pop(); elem != nil {
+			return elem
+		}
+	}
+	return nil
+}
+
+func (s *shardedSet) String() string {
+	return formatString(s)
+}
+
+func (s *shardedSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", s)
+}
+
+func (s *shardedSet) Hash() uint64 {
+	var h setHash
+	for _, shard := range s.shards {
+		shard.rwm.RLock()
+		for elem := range *shard.m {
+			h.add(elem)
+		}
+		shard.rwm.RUnlock()
+	}
+	return h.sum64()
+}
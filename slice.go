@@ -22,6 +22,25 @@ type ISlice interface {
 	Complex128() ([]complex128, error)
 	String() ([]string, error)
 	Bool() ([]bool, error)
+	// AsInt and the other As methods are coercing conversions: they accept any element whose value converts
+	// safely, such as an int32 or a json.Number for AsInt, or a fmt.Stringer for AsString, and report
+	// every element that does not with a *ConversionError.
+	// Examples:
+	// Slice{int32(1), json.Number("2"), 3.0}.AsInt() return []int{1, 2, 3}
+	// Slice{1, 1.5, "a"}.AsInt() return go-set: Slice AsInt() err, 2 invalid values: [1]=1.5, [2]="a"
+	AsInt() ([]int, error)
+	AsInt8() ([]int8, error)
+	AsInt16() ([]int16, error)
+	AsInt32() ([]int32, error)
+	AsInt64() ([]int64, error)
+	AsUint() ([]uint, error)
+	AsUint8() ([]uint8, error)
+	AsUint16() ([]uint16, error)
+	AsUint32() ([]uint32, error)
+	AsUint64() ([]uint64, error)
+	AsFloat32() ([]float32, error)
+	AsFloat64() ([]float64, error)
+	AsString() ([]string, error)
 }
 
 type Slice []interface{}
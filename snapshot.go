@@ -0,0 +1,128 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotSnapshot is returned by Restore when given a set that was not produced by Snapshot.
+var ErrNotSnapshot = errors.New("go-set: Restore() err, not a snapshot")
+
+// errReadOnly is the panic value of the mutating methods of read-only sets, such as snapshots.
+var errReadOnly = errors.New("go-set: set is read-only")
+
+// ISnapshotter is implemented by sets that can take cheap point-in-time snapshots of themselves.
+// NewSet and NewCOWSet return sets implementing it.
+// Examples:
+// s := NewSet(1, 2)
+// snap := s.(ISnapshotter).Snapshot() // {1,2}
+// s.Adds(3)                            // s={1,2,3}, snap={1,2}
+// s.(ISnapshotter).Restore(snap)       // s={1,2}
+type ISnapshotter interface {
+	// Snapshot returns a read-only view of the set as it is now.
+	// The view does not change when the set is mutated afterwards, and its mutating methods panic.
+	// Taking a snapshot does not copy the elements, the set copies them lazily on its next mutation.
+	Snapshot() ISet
+	// Restore replaces the contents of the set with those of a snapshot, which may come from any snapshotter.
+	// It returns ErrNotSnapshot if the given set is not a snapshot.
+	Restore(ISet) error
+}
+
+// snapshotSet is a read-only view of a threadUnsafeSet that nobody mutates anymore.
+type snapshotSet struct {
+	m *threadUnsafeSet
+}
+
+func (s *threadSafeSet) Snapshot() ISet {
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	s.shared = true
+	return &snapshotSet{m: s.m}
+}
+
+func (s *threadSafeSet) Restore(snapshot ISet) error {
+	snap, ok := snapshot.(*snapshotSet)
+	if !ok {
+		return ErrNotSnapshot
+	}
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	s.m, s.shared = snap.m, true
+	return nil
+}
+
+func (s *snapshotSet) Empty() bool {
+	return s.Cardinality() == 0
+}
+
+func (s *snapshotSet) Singleton() bool {
+	return s.Cardinality() == 1
+}
+
+func (s *snapshotSet) Cardinality() int {
+	return s.m.Cardinality()
+}
+
+func (s *snapshotSet) ToSlice() ISlice {
+	return s.m.ToSlice()
+}
+
+func (s *snapshotSet) Adds(...interface{}) bool {
+	panic(errReadOnly)
+}
+
+func (s *snapshotSet) Removes(...interface{}) bool {
+	panic(errReadOnly)
+}
+
+func (s *snapshotSet) IsSub(other ISet) bool {
+	return s.m.IsSub(other)
+}
+
+// Unions returns a new mutable set, see NewSet.
+func (s *snapshotSet) Unions(others ...ISet) ISet {
+	return NewSet(s.m.Unions(others...).ToSlice().Interface()...)
+}
+
+// Intersections returns a new mutable set, see NewSet.
+func (s *snapshotSet) Intersections(others ...ISet) ISet {
+	return NewSet(s.m.Intersections(others...).ToSlice().Interface()...)
+}
+
+// Complements returns a new mutable set, see NewSet.
+func (s *snapshotSet) Complements(others ...ISet) ISet {
+	return NewSet(s.m.Complements(others...).ToSlice().Interface()...)
+}
+
+func (s *snapshotSet) Clear() {
+	panic(errReadOnly)
+}
+
+func (s *snapshotSet) Contains(elems ...interface{}) bool {
+	return s.m.Contains(elems...)
+}
+
+// Clone returns a mutable copy of the snapshot, see NewSet.
+func (s *snapshotSet) Clone() ISet {
+	return NewSet(s.ToSlice().Interface()...)
+}
+
+func (s *snapshotSet) Equal(other ISet) bool {
+	return s.m.Equal(other)
+}
+
+func (s *snapshotSet) Pop() interface{} {
+	panic(errReadOnly)
+}
+
+func (s *snapshotSet) String() string {
+	return s.m.String()
+}
+
+func (s *snapshotSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", s)
+}
+
+func (s *snapshotSet) Hash() uint64 {
+	return s.m.Hash()
+}
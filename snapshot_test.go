@@ -0,0 +1,104 @@
+package set
+
+import (
+	"sync"
+	"testing"
+)
+
+func Test_threadSafeSet_Snapshot(t *testing.T) {
+	s := NewSet(1, 2)
+	snap := s.(ISnapshotter).Snapshot()
+	s.Adds(3)
+	s.Removes(1)
+	if want := NewSet(1, 2); !snap.Equal(want) {
+		t.Errorf("Snapshot() = %v, want %v", snap, want)
+	}
+	if want := NewSet(2, 3); !s.Equal(want) {
+		t.Errorf("Snapshot() source = %v, want %v", s, want)
+	}
+	s.Clear()
+	if want := NewSet(1, 2); !snap.Equal(want) {
+		t.Errorf("Clear() changed snapshot = %v, want %v", snap, want)
+	}
+}
+
+func Test_threadSafeSet_Restore(t *testing.T) {
+	s := NewSet(1, 2)
+	snap := s.(ISnapshotter).Snapshot()
+	s.Adds(3)
+	if err := s.(ISnapshotter).Restore(snap); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if want := NewSet(1, 2); !s.Equal(want) {
+		t.Errorf("Restore() = %v, want %v", s, want)
+	}
+	// The set and the snapshot share storage again, mutating one must not leak into the other.
+	s.Pop()
+	if want := NewSet(1, 2); !snap.Equal(want) {
+		t.Errorf("Restore().Pop() changed snapshot = %v, want %v", snap, want)
+	}
+	if err := s.(ISnapshotter).Restore(NewSet(1)); err != ErrNotSnapshot {
+		t.Errorf("Restore() error = %v, want %v", err, ErrNotSnapshot)
+	}
+	if err := s.(ISnapshotter).Restore(NewCOWSet(5).Snapshot()); err != nil || !s.Equal(NewSet(5)) {
+		t.Errorf("Restore() = %v, %v, want %v", s, err, "{5}")
+	}
+}
+
+func Test_threadSafeSet_SnapshotConcurrent(t *testing.T) {
+	s := NewSet()
+	wg := sync.WaitGroup{}
+	snaps := make(chan ISet, len(elems))
+	for i := range elems {
+		wg.Add(2)
+		go func(i int) {
+			s.Adds(elems[i])
+			wg.Done()
+		}(i)
+		go func() {
+			snaps <- s.(ISnapshotter).Snapshot()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	close(snaps)
+	for snap := range snaps {
+		if n := snap.Cardinality(); n != len(snap.ToSlice().Interface()) || !snap.IsSub(s) {
+			t.Errorf("Snapshot() = %v, not a consistent subset", snap)
+		}
+	}
+	if s.Cardinality() != len(elems) {
+		t.Errorf("Cardinality() = %v, want %v", s.Cardinality(), len(elems))
+	}
+}
+
+func Test_snapshotSet_ReadOnly(t *testing.T) {
+	snap := NewSet(1, 2).(ISnapshotter).Snapshot()
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{name: "Adds", fn: func() { snap.Adds(3) }},
+		{name: "Removes", fn: func() { snap.Removes(1) }},
+		{name: "Clear", fn: func() { snap.Clear() }},
+		{name: "Pop", fn: func() { snap.Pop() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != errReadOnly {
+					t.Errorf("%v() recover = %v, want %v", tt.name, r, errReadOnly)
+				}
+			}()
+			tt.fn()
+		})
+	}
+	clone := snap.Clone()
+	clone.Adds(3)
+	if want := NewSet(1, 2, 3); !clone.Equal(want) {
+		t.Errorf("Clone() = %v, want %v", clone, want)
+	}
+	if got, want := snap.Complements(NewSet(1)), NewSet(2); !got.Equal(want) {
+		t.Errorf("Complements() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,509 @@
+package set
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Sorted file layout, all integers little endian:
+//
+//	header  magic "GOSETSF1"
+//	blocks  per element: uvarint length, encoded element; elements in ascending encoded order
+//	index   per block: uint64 offset of its first element
+//	footer  uint64 index offset, uint64 block count, uint64 element count, magic "GOSETSF1"
+const (
+	sortedFileMagic      = "GOSETSF1"
+	sortedFileFooterSize = 24 + len(sortedFileMagic)
+	// DefaultSortedFileBlockSize is the number of elements per block used by BuildSortedFile.
+	DefaultSortedFileBlockSize = 64
+)
+
+// ErrSortedFileOrder is returned by SortedFileWriter.Add when elements are not added in ascending order.
+var ErrSortedFileOrder = errors.New("go-set: SortedFileWriter err, elements out of order")
+
+// BuildSortedFile writes the elements of s to a new sorted file at path, see OpenSortedFile.
+// Elements must be nil, booleans, numbers or strings, see WriteSet.
+func BuildSortedFile(path string, s ISet) error {
+	encs, err := sortedEncodings(s.ToSlice().Interface())
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := NewSortedFileWriter(f, DefaultSortedFileBlockSize)
+	for _, enc := range encs {
+		if err := w.addEncoded(enc); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sortedEncodings returns the encodings of elems in sorted file order.
+func sortedEncodings(elems []interface{}) ([][]byte, error) {
+	encs := make([][]byte, 0, len(elems))
+	for _, elem := range elems {
+		enc, err := appendElem(nil, elem)
+		if err != nil {
+			return nil, err
+		}
+		encs = append(encs, enc)
+	}
+	sort.Slice(encs, func(i, j int) bool { return bytes.Compare(encs[i], encs[j]) < 0 })
+	return encs, nil
+}
+
+// NewSortedFileWriter returns a writer streaming a sorted file to w, blockSize elements per block.
+// Elements must be added in the order SortedFile iterates them, which is the byte order of their binary encoding;
+// BuildSortedFile sorts them for you. Close must be called to write the index.
+func NewSortedFileWriter(w io.Writer, blockSize int) *SortedFileWriter {
+	if blockSize < 1 {
+		blockSize = DefaultSortedFileBlockSize
+	}
+	return &SortedFileWriter{w: bufio.NewWriter(w), blockSize: blockSize}
+}
+
+// SortedFileWriter writes a sorted file element by element, see NewSortedFileWriter.
+type SortedFileWriter struct {
+	w         *bufio.Writer
+	blockSize int
+	offset    uint64
+	index     []uint64
+	count     uint64
+	last      []byte
+	err       error
+}
+
+// Add appends an element. Adding the previous element again is a no-op,
+// adding a smaller one returns ErrSortedFileOrder.
+func (w *SortedFileWriter) Add(elem interface{}) error {
+	enc, err := appendElem(nil, elem)
+	if err != nil {
+		return err
+	}
+	return w.addEncoded(enc)
+}
+
+func (w *SortedFileWriter) addEncoded(enc []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.count > 0 {
+		switch c := bytes.Compare(enc, w.last); {
+		case c == 0:
+			return nil
+		case c < 0:
+			return ErrSortedFileOrder
+		}
+	} else if err := w.write([]byte(sortedFileMagic)); err != nil {
+		return err
+	}
+	if w.count%uint64(w.blockSize) == 0 {
+		w.index = append(w.index, w.offset)
+	}
+	if err := w.write(appendUvarint(nil, uint64(len(enc)))); err != nil {
+		return err
+	}
+	if err := w.write(enc); err != nil {
+		return err
+	}
+	w.last = append(w.last[:0], enc...)
+	w.count++
+	return nil
+}
+
+func (w *SortedFileWriter) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += uint64(n)
+	if err != nil {
+		w.err = err
+	}
+	return err
+}
+
+// Close writes the index and footer and flushes them. It does not close the underlying writer.
+func (w *SortedFileWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.count == 0 {
+		if err := w.write([]byte(sortedFileMagic)); err != nil {
+			return err
+		}
+	}
+	indexOffset := w.offset
+	var buf []byte
+	for _, off := range w.index {
+		buf = appendUint64(buf, off)
+	}
+	buf = appendUint64(buf, indexOffset)
+	buf = appendUint64(buf, uint64(len(w.index)))
+	buf = appendUint64(buf, w.count)
+	buf = append(buf, sortedFileMagic...)
+	if err := w.write(buf); err != nil {
+		return err
+	}
+	if err := w.w.Flush(); err != nil {
+		w.err = err
+		return err
+	}
+	w.err = errors.New("go-set: SortedFileWriter err, closed")
+	return nil
+}
+
+// OpenSortedFile memory-maps a file written by BuildSortedFile or SortedFileWriter.
+// Nothing is loaded up front: Contains binary searches the block index in place and
+// iteration streams the blocks, so the operating system pages in only what is touched.
+// On platforms without mmap support the file is read into memory instead.
+func OpenSortedFile(path string) (*SortedFile, error) {
+	data, closer, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &SortedFile{data: data, closer: closer}
+	if err := s.parse(); err != nil {
+		closer()
+		return nil, fmt.Errorf("go-set: OpenSortedFile() err, %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// SortedFile is an immutable, memory-mapped ISet, see OpenSortedFile.
+// It is safe for concurrent use. Its mutating methods panic, Clone and the set algebra methods
+// return in-memory sets (see NewSet), and it must not be used after Close.
+type SortedFile struct {
+	data   []byte
+	closer func() error
+	index  []byte // the block offsets
+	blocks int
+	count  int
+
+	errMu sync.Mutex
+	err   error // the first corruption found, see Err
+}
+
+func (s *SortedFile) parse() error {
+	if len(s.data) < len(sortedFileMagic)+sortedFileFooterSize ||
+		string(s.data[:len(sortedFileMagic)]) != sortedFileMagic ||
+		string(s.data[len(s.data)-len(sortedFileMagic):]) != sortedFileMagic {
+		return errors.New("not a sorted file")
+	}
+	footer := s.data[len(s.data)-sortedFileFooterSize:]
+	indexOffset := binary.LittleEndian.Uint64(footer)
+	blocks := binary.LittleEndian.Uint64(footer[8:])
+	count := binary.LittleEndian.Uint64(footer[16:])
+	indexEnd := uint64(len(s.data) - sortedFileFooterSize)
+	if indexOffset > indexEnd || (indexEnd-indexOffset)/8 != blocks || (indexEnd-indexOffset)%8 != 0 {
+		return errors.New("corrupt index")
+	}
+	if count > indexOffset || count < blocks || (blocks == 0) != (count == 0) {
+		return errors.New("corrupt element count")
+	}
+	s.index = s.data[indexOffset:indexEnd]
+	s.blocks, s.count = int(blocks), int(count)
+	// Blocks start right after the header, in order, and are not empty. Their contents are only checked
+	// when read, so opening does not page in the whole file.
+	prev := uint64(len(sortedFileMagic)) - 1
+	for i := 0; i < s.blocks; i++ {
+		off := binary.LittleEndian.Uint64(s.index[8*i:])
+		if (i == 0 && off != uint64(len(sortedFileMagic))) || off <= prev || off >= indexOffset {
+			return fmt.Errorf("corrupt offset %d of block %d", off, i)
+		}
+		prev = off
+	}
+	if s.blocks == 0 && indexOffset != uint64(len(sortedFileMagic)) {
+		return errors.New("corrupt index offset")
+	}
+	return nil
+}
+
+// Err returns the first corruption found while reading the file, nil if none was.
+// OpenSortedFile only checks the index: a read that meets corrupt elements stops there, as if the set ended,
+// so Contains returns false and Range stops early, and Err reports why.
+func (s *SortedFile) Err() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
+
+func (s *SortedFile) fail(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err == nil {
+		s.err = fmt.Errorf("go-set: SortedFile err, %v", err)
+	}
+}
+
+// Close unmaps the file.
+func (s *SortedFile) Close() error {
+	s.data, s.index = nil, nil
+	return s.closer()
+}
+
+func (s *SortedFile) blockOffset(i int) int {
+	return int(binary.LittleEndian.Uint64(s.index[8*i:]))
+}
+
+// entry returns the encoded element at off and the offset of the next one, which must not go past end.
+func (s *SortedFile) entry(off, end int) ([]byte, int, error) {
+	l, n := binary.Uvarint(s.data[off:end])
+	if n <= 0 || l > uint64(end-off-n) {
+		return nil, 0, fmt.Errorf("corrupt element at offset %d", off)
+	}
+	start := off + n
+	return s.data[start : start+int(l)], start + int(l), nil
+}
+
+func (s *SortedFile) blockEnd(i int) int {
+	if i+1 < s.blocks {
+		return s.blockOffset(i + 1)
+	}
+	return len(s.data) - sortedFileFooterSize - len(s.index)
+}
+
+func (s *SortedFile) containsEncoded(enc []byte) bool {
+	// Find the last block whose first element is <= enc.
+	var err error
+	i := sort.Search(s.blocks, func(i int) bool {
+		first, _, ferr := s.entry(s.blockOffset(i), s.blockEnd(i))
+		if ferr != nil {
+			err = ferr
+		}
+		return bytes.Compare(first, enc) > 0
+	}) - 1
+	if err != nil {
+		s.fail(err)
+		return false
+	}
+	if i < 0 {
+		return false
+	}
+	for off, end := s.blockOffset(i), s.blockEnd(i); off < end; {
+		var cur []byte
+		if cur, off, err = s.entry(off, end); err != nil {
+			s.fail(err)
+			return false
+		}
+		switch c := bytes.Compare(cur, enc); {
+		case c == 0:
+			return true
+		case c > 0:
+			return false
+		}
+	}
+	return false
+}
+
+// Range calls fn for each element, in ascending encoded order, until fn returns false
+// or it meets a corrupt element, see Err.
+func (s *SortedFile) Range(fn func(elem interface{}) bool) {
+	s.rangeEncoded(func(enc []byte) bool {
+		elem, _, err := decodeElem(enc)
+		if err != nil {
+			s.fail(err)
+			return false
+		}
+		return fn(elem)
+	})
+}
+
+func (s *SortedFile) rangeEncoded(fn func(enc []byte) bool) {
+	if s.blocks == 0 {
+		return
+	}
+	for off, end := s.blockOffset(0), s.blockEnd(s.blocks-1); off < end; {
+		cur, next, err := s.entry(off, end)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		if off = next; !fn(cur) {
+			return
+		}
+	}
+}
+
+// Iter returns an Iterator over the elements, in ascending encoded order.
+// Call Stop if the iteration is abandoned early.
+func (s *SortedFile) Iter() *Iterator {
+	iterator, ch, stopCh := newIterator()
+	go func() {
+		defer close(ch)
+		s.Range(func(elem interface{}) bool {
+			select {
+			case <-stopCh:
+				return false
+			case ch <- elem:
+				return true
+			}
+		})
+	}()
+	return iterator
+}
+
+func (s *SortedFile) Empty() bool {
+	return s.Cardinality() == 0
+}
+
+func (s *SortedFile) Singleton() bool {
+	return s.Cardinality() == 1
+}
+
+func (s *SortedFile) Cardinality() int {
+	return s.count
+}
+
+// ToSlice loads every element into memory.
+func (s *SortedFile) ToSlice() ISlice {
+	result := make(Slice, 0, s.count)
+	s.Range(func(elem interface{}) bool {
+		result = append(result, elem)
+		return true
+	})
+	return result
+}
+
+func (s *SortedFile) Adds(...interface{}) bool {
+	panic(errReadOnly)
+}
+
+func (s *SortedFile) Removes(...interface{}) bool {
+	panic(errReadOnly)
+}
+
+func (s *SortedFile) IsSub(other ISet) bool {
+	if s.Cardinality() > other.Cardinality() {
+		return false
+	}
+	result := true
+	s.Range(func(elem interface{}) bool {
+		result = other.Contains(elem)
+		return result
+	})
+	return result
+}
+
+// Unions loads every element into memory.
+func (s *SortedFile) Unions(others ...ISet) ISet {
+	result := s.Clone()
+	for _, other := range others {
+		result.Adds(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+// Intersections only loads the elements of the result, when another set is smaller than the file
+// only that set is scanned.
+func (s *SortedFile) Intersections(others ...ISet) ISet {
+	result := NewSet()
+	var baseSet ISet = s
+	var diffSets []ISet
+	for _, other := range others {
+		if other.Cardinality() < baseSet.Cardinality() {
+			diffSets = append(diffSets, baseSet)
+			baseSet = other
+		} else {
+			diffSets = append(diffSets, other)
+		}
+	}
+	keep := func(elem interface{}) bool {
+		for _, diffSet := range diffSets {
+			if !diffSet.Contains(elem) {
+				return true
+			}
+		}
+		result.Adds(elem)
+		return true
+	}
+	if baseSet == ISet(s) {
+		s.Range(keep)
+	} else {
+		for _, elem := range baseSet.ToSlice().Interface() {
+			keep(elem)
+		}
+	}
+	return result
+}
+
+// Complements streams the file and only loads the elements of the result.
+func (s *SortedFile) Complements(others ...ISet) ISet {
+	result := NewSet()
+	s.Range(func(elem interface{}) bool {
+		for _, other := range others {
+			if other.Contains(elem) {
+				return true
+			}
+		}
+		result.Adds(elem)
+		return true
+	})
+	return result
+}
+
+func (s *SortedFile) Clear() {
+	panic(errReadOnly)
+}
+
+// Contains returns whether the given items are all in the file, elements that cannot be encoded never are.
+func (s *SortedFile) Contains(elems ...interface{}) bool {
+	var buf []byte
+	for i := 0; i < len(elems); i++ {
+		enc, err := appendElem(buf[:0], elems[i])
+		if err != nil || !s.containsEncoded(enc) {
+			return false
+		}
+		buf = enc
+	}
+	return true
+}
+
+// Clone loads every element into a new in-memory set, see NewSet.
+func (s *SortedFile) Clone() ISet {
+	return NewSet(s.ToSlice().Interface()...)
+}
+
+func (s *SortedFile) Equal(other ISet) bool {
+	if other.Cardinality() != s.Cardinality() {
+		return false
+	}
+	return s.IsSub(other)
+}
+
+func (s *SortedFile) Pop() interface{} {
+	panic(errReadOnly)
+}
+
+func (s *SortedFile) String() string {
+	return formatString(s)
+}
+
+func (s *SortedFile) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", s)
+}
+
+func (s *SortedFile) Hash() uint64 {
+	var h setHash
+	s.Range(func(elem interface{}) bool {
+		h.add(elem)
+		return true
+	})
+	return h.sum64()
+}
@@ -0,0 +1,197 @@
+package set
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func buildTestSortedFile(t *testing.T, s ISet) *SortedFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "set.sorted")
+	if err := BuildSortedFile(path, s); err != nil {
+		t.Fatalf("BuildSortedFile() error = %v", err)
+	}
+	f, err := OpenSortedFile(path)
+	if err != nil {
+		t.Fatalf("OpenSortedFile() error = %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestSortedFile_Contains(t *testing.T) {
+	domains := NewSet()
+	for i := 0; i < 1000; i++ {
+		domains.Adds(fmt.Sprintf("host%d.example.com", i))
+	}
+	f := buildTestSortedFile(t, domains)
+	if f.Cardinality() != domains.Cardinality() {
+		t.Errorf("Cardinality() = %v, want %v", f.Cardinality(), domains.Cardinality())
+	}
+	for _, elem := range domains.ToSlice().Interface() {
+		if !f.Contains(elem) {
+			t.Errorf("Contains(%v) = %v, want %v", elem, false, true)
+		}
+	}
+	tests := []interface{}{"", "host", "host1000.example.com", "zzz", 1, []int{1}}
+	for _, elem := range tests {
+		if f.Contains(elem) {
+			t.Errorf("Contains(%v) = %v, want %v", elem, true, false)
+		}
+	}
+	if !f.Equal(domains) || !domains.Equal(f) {
+		t.Errorf("Equal() = %v, want %v", false, true)
+	}
+}
+
+func TestSortedFile_Mixed(t *testing.T) {
+	s := NewSet(nil, true, -1, int64(2), uint8(3), 1.5, "a")
+	f := buildTestSortedFile(t, s)
+	if got := f.Clone(); !got.Equal(s) {
+		t.Errorf("Clone() = %v, want %v", got, s)
+	}
+	var n int
+	for elem := range f.Iter().C {
+		if !s.Contains(elem) {
+			t.Errorf("Iter() = %v, not in %v", elem, s)
+		}
+		n++
+	}
+	if n != s.Cardinality() {
+		t.Errorf("Iter() visited %v, want %v", n, s.Cardinality())
+	}
+}
+
+func TestSortedFile_Empty(t *testing.T) {
+	f := buildTestSortedFile(t, NewSet())
+	if !f.Empty() || f.Contains("a") || f.String() != "{}" {
+		t.Errorf("Empty() = %v, want %v", f, "{}")
+	}
+}
+
+func TestSortedFile_Algebra(t *testing.T) {
+	f := buildTestSortedFile(t, NewSet(1, 2, 3, 4))
+	if got, want := f.Unions(NewSet(5)), NewSet(1, 2, 3, 4, 5); !got.Equal(want) {
+		t.Errorf("Unions() = %v, want %v", got, want)
+	}
+	if got, want := f.Intersections(NewSet(2, 3, 9)), NewSet(2, 3); !got.Equal(want) {
+		t.Errorf("Intersections() = %v, want %v", got, want)
+	}
+	if got, want := f.Intersections(NewSet(1, 2, 3, 4, 5, 6)), NewSet(1, 2, 3, 4); !got.Equal(want) {
+		t.Errorf("Intersections() = %v, want %v", got, want)
+	}
+	if got, want := f.Complements(NewSet(1, 3)), NewSet(2, 4); !got.Equal(want) {
+		t.Errorf("Complements() = %v, want %v", got, want)
+	}
+	if !f.IsSub(NewSet(0, 1, 2, 3, 4)) || f.IsSub(NewSet(1, 2, 3, 5)) {
+		t.Errorf("IsSub() = %v, want %v", !f.IsSub(NewSet(0, 1, 2, 3, 4)), true)
+	}
+	if !NewSet(2, 4).IsSub(f) {
+		t.Errorf("IsSub() = %v, want %v", false, true)
+	}
+	defer func() {
+		if r := recover(); r != errReadOnly {
+			t.Errorf("Adds() recover = %v, want %v", r, errReadOnly)
+		}
+	}()
+	f.Adds(5)
+}
+
+func TestSortedFileWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSortedFileWriter(&buf, 2)
+	for _, elem := range []string{"a", "b", "b", "c", "d", "e"} {
+		if err := w.Add(elem); err != nil {
+			t.Fatalf("Add(%v) error = %v", elem, err)
+		}
+	}
+	if err := w.Add("a"); err != ErrSortedFileOrder {
+		t.Errorf("Add() error = %v, want %v", err, ErrSortedFileOrder)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "set.sorted")
+	ioutil.WriteFile(path, buf.Bytes(), 0o644)
+	f, err := OpenSortedFile(path)
+	if err != nil {
+		t.Fatalf("OpenSortedFile() error = %v", err)
+	}
+	defer f.Close()
+	if want := NewSet("a", "b", "c", "d", "e"); !f.Equal(want) || f.blocks != 3 {
+		t.Errorf("OpenSortedFile() = %v in %v blocks, want %v in 3 blocks", f, f.blocks, want)
+	}
+
+	ioutil.WriteFile(path, []byte("not a sorted file"), 0o644)
+	if _, err := OpenSortedFile(path); err == nil {
+		t.Errorf("OpenSortedFile() error = %v, want an error", err)
+	}
+}
+
+func TestSortedFile_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSortedFileWriter(&buf, 2)
+	for _, elem := range []string{"a", "b", "c", "d", "e"} {
+		w.Add(elem)
+	}
+	w.Close()
+	good := buf.Bytes()
+	path := filepath.Join(t.TempDir(), "set.sorted")
+	open := func(data []byte) (*SortedFile, error) {
+		ioutil.WriteFile(path, data, 0o644)
+		return OpenSortedFile(path)
+	}
+	indexOffset := len(good) - sortedFileFooterSize - 3*8
+
+	badOffset := append([]byte(nil), good...)
+	badOffset[indexOffset+8] = 0xff
+	if f, err := open(badOffset); err == nil {
+		f.Close()
+		t.Errorf("OpenSortedFile() with a corrupt block offset error = %v, want an error", err)
+	}
+
+	badLength := append([]byte(nil), good...)
+	badLength[len(sortedFileMagic)] = 0x7f
+	f, err := open(badLength)
+	if err != nil {
+		t.Fatalf("OpenSortedFile() error = %v", err)
+	}
+	if f.Contains("a") || f.Err() == nil {
+		t.Errorf("Contains() of a corrupt element = %v, Err() = %v, want false and an error", true, f.Err())
+	}
+	f.Close()
+
+	badTag := append([]byte(nil), good...)
+	badTag[len(sortedFileMagic)+1] = 0xff
+	if f, err = open(badTag); err != nil {
+		t.Fatalf("OpenSortedFile() error = %v", err)
+	}
+	var n int
+	f.Range(func(interface{}) bool { n++; return true })
+	if n != 0 || f.Err() == nil {
+		t.Errorf("Range() over a corrupt element visited %d, Err() = %v, want 0 and an error", n, f.Err())
+	}
+	f.Close()
+
+	// No corruption panics.
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		data := append([]byte(nil), good...)
+		if i%2 == 0 {
+			data = data[:1+r.Intn(len(data)-1)]
+		}
+		for j := r.Intn(3); j >= 0; j-- {
+			data[r.Intn(len(data))] ^= byte(1 + r.Intn(255))
+		}
+		if f, err := open(data); err == nil {
+			f.Contains("c", "z")
+			f.Range(func(interface{}) bool { return true })
+			_ = f.String()
+			f.Close()
+		}
+	}
+}
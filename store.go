@@ -0,0 +1,634 @@
+package set
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewStore returns an empty keyspace of named sets, reading the time for key expiry from now, time.Now if nil.
+func NewStore(now func() time.Time) *Store {
+	if now == nil {
+		now = time.Now
+	}
+	return &Store{keys: make(map[string]*storeEntry), now: now}
+}
+
+// Store is a thread safe keyspace of named sets, with commands mirroring the set commands of Redis.
+//
+// As in Redis, a key holding no element does not exist: commands read missing keys as empty sets,
+// and a set that becomes empty is deleted, along with its expiry.
+// Every key has its own lock, so commands on different keys run in parallel, and commands on
+// several keys lock them in key order, so they are atomic and cannot deadlock.
+// Expired keys are treated as missing and deleted on their next write, or by Sweep.
+type Store struct {
+	rwm  sync.RWMutex
+	keys map[string]*storeEntry
+	now  func() time.Time
+}
+
+type storeEntry struct {
+	rwm     sync.RWMutex
+	m       threadUnsafeSet
+	expires time.Time // zero if the key does not expire
+	dead    bool      // deleted from the store, the key must be looked up again
+}
+
+func (e *storeEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// entry returns the entry of key, creating it if create.
+func (st *Store) entry(key string, create bool) *storeEntry {
+	st.rwm.RLock()
+	e := st.keys[key]
+	st.rwm.RUnlock()
+	if e != nil || !create {
+		return e
+	}
+	st.rwm.Lock()
+	defer st.rwm.Unlock()
+	if e = st.keys[key]; e == nil {
+		e = &storeEntry{m: make(threadUnsafeSet)}
+		st.keys[key] = e
+	}
+	return e
+}
+
+// remove deletes the entry of key. The caller must hold the write lock of e.
+func (st *Store) remove(key string, e *storeEntry) {
+	st.rwm.Lock()
+	if st.keys[key] == e {
+		delete(st.keys, key)
+	}
+	st.rwm.Unlock()
+	e.dead = true
+}
+
+// with locks the entries of the keys read, for reading, and of the keys written, for writing, in key order,
+// and runs fn on them. entries holds the live entries read, and every entry written, created if missing and
+// emptied if expired. The entries written that fn leaves empty are deleted.
+func (st *Store) with(read, write []string, fn func(entries map[string]*storeEntry)) {
+	writes := make(map[string]bool, len(read)+len(write))
+	for _, key := range read {
+		writes[key] = false
+	}
+	for _, key := range write {
+		writes[key] = true
+	}
+	keys := make([]string, 0, len(writes))
+	for key := range writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	locked := make([]*storeEntry, len(keys))
+	// unlock releases the entries locked so far, and forgets them so a retry starts afresh.
+	unlock := func() {
+		for i, e := range locked {
+			switch {
+			case e == nil:
+			case writes[keys[i]]:
+				e.rwm.Unlock()
+			default:
+				e.rwm.RUnlock()
+			}
+			locked[i] = nil
+		}
+	}
+Retry:
+	for {
+		for i, key := range keys {
+			if e := st.entry(key, writes[key]); e != nil {
+				if writes[key] {
+					e.rwm.Lock()
+				} else {
+					e.rwm.RLock()
+				}
+				locked[i] = e
+				if e.dead {
+					unlock()
+					continue Retry
+				}
+			}
+		}
+		break
+	}
+	defer unlock()
+	now := st.now()
+	entries := make(map[string]*storeEntry, len(keys))
+	for i, e := range locked {
+		switch {
+		case e == nil:
+		case !e.expired(now):
+			entries[keys[i]] = e
+		case writes[keys[i]]:
+			e.m, e.expires = make(threadUnsafeSet), time.Time{}
+			entries[keys[i]] = e
+		}
+	}
+	fn(entries)
+	for i, e := range locked {
+		if e != nil && writes[keys[i]] && len(e.m) == 0 {
+			st.remove(keys[i], e)
+		}
+	}
+}
+
+// members returns the set of key in entries, nil if it is missing.
+func members(entries map[string]*storeEntry, key string) threadUnsafeSet {
+	if e, ok := entries[key]; ok {
+		return e.m
+	}
+	return nil
+}
+
+// SAdd adds members to the set of key, creating it if needed, and returns the number of members added.
+func (st *Store) SAdd(key string, members ...interface{}) (added int) {
+	st.with(nil, []string{key}, func(entries map[string]*storeEntry) {
+		m := entries[key].m
+		for _, member := range members {
+			if _, ok := m[member]; !ok {
+				m[member] = struct{}{}
+				added++
+			}
+		}
+	})
+	return added
+}
+
+// SRem removes members from the set of key, and returns the number of members removed.
+func (st *Store) SRem(key string, members ...interface{}) (removed int) {
+	st.with(nil, []string{key}, func(entries map[string]*storeEntry) {
+		m := entries[key].m
+		for _, member := range members {
+			if _, ok := m[member]; ok {
+				delete(m, member)
+				removed++
+			}
+		}
+	})
+	return removed
+}
+
+// SIsMember returns whether member is in the set of key.
+func (st *Store) SIsMember(key string, member interface{}) (ok bool) {
+	st.with([]string{key}, nil, func(entries map[string]*storeEntry) {
+		_, ok = members(entries, key)[member]
+	})
+	return ok
+}
+
+// SMembers returns a copy of the set of key, empty if it is missing, see NewSet.
+func (st *Store) SMembers(key string) (s ISet) {
+	st.with([]string{key}, nil, func(entries map[string]*storeEntry) {
+		s = NewSet(elemsOf(members(entries, key))...)
+	})
+	return s
+}
+
+// SCard returns the number of members of the set of key.
+func (st *Store) SCard(key string) (n int) {
+	st.with([]string{key}, nil, func(entries map[string]*storeEntry) {
+		n = len(members(entries, key))
+	})
+	return n
+}
+
+func elemsOf(m threadUnsafeSet) []interface{} {
+	elems := make([]interface{}, 0, len(m))
+	for elem := range m {
+		elems = append(elems, elem)
+	}
+	return elems
+}
+
+type storeOp func(sets []threadUnsafeSet) threadUnsafeSet
+
+func unionOp(sets []threadUnsafeSet) threadUnsafeSet {
+	result := make(threadUnsafeSet)
+	for _, s := range sets {
+		for elem := range s {
+			result[elem] = struct{}{}
+		}
+	}
+	return result
+}
+
+// interOp probes the members of the smallest set in the others, from the smallest to the largest.
+func interOp(sets []threadUnsafeSet) threadUnsafeSet {
+	result := make(threadUnsafeSet)
+	if len(sets) == 0 {
+		return result
+	}
+	sets = append([]threadUnsafeSet(nil), sets...)
+	sort.SliceStable(sets, func(i, j int) bool { return len(sets[i]) < len(sets[j]) })
+Loop:
+	for elem := range sets[0] {
+		for _, other := range sets[1:] {
+			if _, ok := other[elem]; !ok {
+				continue Loop
+			}
+		}
+		result[elem] = struct{}{}
+	}
+	return result
+}
+
+func diffOp(sets []threadUnsafeSet) threadUnsafeSet {
+	result := make(threadUnsafeSet)
+	if len(sets) == 0 {
+		return result
+	}
+Loop:
+	for elem := range sets[0] {
+		for _, other := range sets[1:] {
+			if _, ok := other[elem]; ok {
+				continue Loop
+			}
+		}
+		result[elem] = struct{}{}
+	}
+	return result
+}
+
+// combine applies op to the sets of keys, and stores the result in dst if store.
+func (st *Store) combine(op storeOp, keys []string, dst string, store bool) (result threadUnsafeSet) {
+	var write []string
+	if store {
+		write = []string{dst}
+	}
+	st.with(keys, write, func(entries map[string]*storeEntry) {
+		sets := make([]threadUnsafeSet, len(keys))
+		for i, key := range keys {
+			sets[i] = members(entries, key)
+		}
+		result = op(sets)
+		if store {
+			entries[dst].m, entries[dst].expires = result, time.Time{}
+		}
+	})
+	return result
+}
+
+// SUnion returns the union of the sets of keys, see NewSet.
+func (st *Store) SUnion(keys ...string) ISet {
+	return NewSet(elemsOf(st.combine(unionOp, keys, "", false))...)
+}
+
+// SInter returns the intersection of the sets of keys, see NewSet.
+func (st *Store) SInter(keys ...string) ISet {
+	return NewSet(elemsOf(st.combine(interOp, keys, "", false))...)
+}
+
+// SDiff returns the members of the set of the first key that are in none of the others, see NewSet.
+func (st *Store) SDiff(keys ...string) ISet {
+	return NewSet(elemsOf(st.combine(diffOp, keys, "", false))...)
+}
+
+// SUnionStore is like SUnion, but replaces the set of dst, and its expiry, with the result,
+// deleting dst if the result is empty. It returns the number of members of the result.
+func (st *Store) SUnionStore(dst string, keys ...string) int {
+	return len(st.combine(unionOp, keys, dst, true))
+}
+
+// SInterStore is like SInter, but stores the result in dst, see SUnionStore.
+func (st *Store) SInterStore(dst string, keys ...string) int {
+	return len(st.combine(interOp, keys, dst, true))
+}
+
+// SDiffStore is like SDiff, but stores the result in dst, see SUnionStore.
+func (st *Store) SDiffStore(dst string, keys ...string) int {
+	return len(st.combine(diffOp, keys, dst, true))
+}
+
+// SMove atomically moves member from the set of src to the set of dst, and returns whether it was in src.
+func (st *Store) SMove(src, dst string, member interface{}) (moved bool) {
+	st.with(nil, []string{src, dst}, func(entries map[string]*storeEntry) {
+		if _, moved = entries[src].m[member]; moved {
+			delete(entries[src].m, member)
+			entries[dst].m[member] = struct{}{}
+		}
+	})
+	return moved
+}
+
+// SPop removes and returns a random member of the set of key, ok is false if it is missing.
+func (st *Store) SPop(key string) (member interface{}, ok bool) {
+	if popped := st.SPopN(key, 1); len(popped) == 1 {
+		return popped[0], true
+	}
+	return nil, false
+}
+
+// SPopN removes and returns up to count random members of the set of key.
+func (st *Store) SPopN(key string, count int) (popped []interface{}) {
+	st.with(nil, []string{key}, func(entries map[string]*storeEntry) {
+		m := entries[key].m
+		for _, elem := range randomMembers(m, count) {
+			delete(m, elem)
+			popped = append(popped, elem)
+		}
+	})
+	return popped
+}
+
+// SRandMember returns a random member of the set of key, ok is false if it is missing.
+func (st *Store) SRandMember(key string) (member interface{}, ok bool) {
+	if members := st.SRandMemberN(key, 1); len(members) == 1 {
+		return members[0], true
+	}
+	return nil, false
+}
+
+// SRandMemberN returns random members of the set of key: up to count distinct members if count is positive,
+// or exactly -count members, possibly repeated, if it is negative.
+func (st *Store) SRandMemberN(key string, count int) (result []interface{}) {
+	st.with([]string{key}, nil, func(entries map[string]*storeEntry) {
+		m := members(entries, key)
+		if count >= 0 || len(m) == 0 {
+			result = randomMembers(m, count)
+			return
+		}
+		all := elemsOf(m)
+		for i := 0; i < -count; i++ {
+			result = append(result, all[rand.Intn(len(all))])
+		}
+	})
+	return result
+}
+
+// randomMembers returns up to count distinct random members of m, with a partial Fisher-Yates shuffle.
+func randomMembers(m threadUnsafeSet, count int) []interface{} {
+	if count <= 0 || len(m) == 0 {
+		return nil
+	}
+	all := elemsOf(m)
+	if count > len(all) {
+		count = len(all)
+	}
+	for i := 0; i < count; i++ {
+		j := i + rand.Intn(len(all)-i)
+		all[i], all[j] = all[j], all[i]
+	}
+	return all[:count]
+}
+
+// SScan iterates over the set of key, count members at a time, 10 if count is not positive.
+// Start with cursor 0, and call again with the returned cursor until it is 0.
+// Like in Redis, every member present during the whole iteration is returned at least once,
+// members added or removed meanwhile may or may not be, and a call may return more than count members.
+// match, if not empty, is a Redis glob pattern that the members, printed with %v, must match.
+//
+// The set keeps no order, so every call hashes all its n members to find the next page, in O(n log count):
+// a full iteration costs O(n²/count log count). Use large counts, or SMembers, to read a large set whole.
+func (st *Store) SScan(key string, cursor uint64, match string, count int) (next uint64, result []interface{}) {
+	if count <= 0 {
+		count = 10
+	}
+	// Members are visited in the order of their hashes, which does not depend on the others,
+	// so the cursor is the hash to resume from. The page holds the members whose hashes are among the
+	// count smallest ones from the cursor on, so members with equal hashes are returned together.
+	st.with([]string{key}, nil, func(entries map[string]*storeEntry) {
+		m := members(entries, key)
+		smallest := make(hashHeap, 0, count)
+		for elem := range m {
+			if h := hashOf(elem); h >= cursor {
+				switch {
+				case len(smallest) < count:
+					heap.Push(&smallest, h)
+				case h < smallest[0]:
+					smallest[0] = h
+					heap.Fix(&smallest, 0)
+				}
+			}
+		}
+		if len(smallest) == 0 {
+			return
+		}
+		bound := smallest[0]
+		for elem := range m {
+			h := hashOf(elem)
+			switch {
+			case h < cursor:
+			case h > bound:
+				if next == 0 || h < next {
+					next = h
+				}
+			case match == "" || matchGlob(match, memberString(elem)):
+				result = append(result, elem)
+			}
+		}
+	})
+	return next, result
+}
+
+// hashHeap is a max-heap of hashes.
+type hashHeap []uint64
+
+func (h hashHeap) Len() int            { return len(h) }
+func (h hashHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h hashHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hashHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *hashHeap) Pop() interface{} {
+	x := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return x
+}
+
+func memberString(elem interface{}) string {
+	if s, ok := elem.(string); ok {
+		return s
+	}
+	return fmt.Sprint(elem)
+}
+
+// matchGlob reports whether s matches the Redis glob pattern: * matches any run of characters,
+// ? any character, [abc], [^abc] and [a-z] a character class, and \ escapes the next character.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end, ok := matchClass(pattern, s[0])
+			if !ok {
+				return false
+			}
+			pattern, s = pattern[end:], s[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass matches c against the character class at the start of pattern,
+// and returns the length of the class.
+func matchClass(pattern string, c byte) (end int, ok bool) {
+	i := 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+	var match bool
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		hi := lo
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			hi = pattern[i+2]
+			i += 2
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+		}
+		if lo <= c && c <= hi {
+			match = true
+		}
+	}
+	if i < len(pattern) {
+		i++ // the closing ]
+	}
+	return i, match != negate
+}
+
+// Del deletes keys, and returns the number of keys that existed.
+func (st *Store) Del(keys ...string) (deleted int) {
+	st.with(nil, keys, func(entries map[string]*storeEntry) {
+		for _, e := range entries {
+			if len(e.m) > 0 {
+				e.m = make(threadUnsafeSet)
+				deleted++
+			}
+		}
+	})
+	return deleted
+}
+
+// Exists returns how many of keys exist, counting a key as many times as it is given.
+func (st *Store) Exists(keys ...string) (n int) {
+	st.with(keys, nil, func(entries map[string]*storeEntry) {
+		for _, key := range keys {
+			if len(members(entries, key)) > 0 {
+				n++
+			}
+		}
+	})
+	return n
+}
+
+// Keys returns the sorted keys that match the Redis glob pattern, every key if it is empty, see SScan.
+func (st *Store) Keys(pattern string) []string {
+	now := st.now()
+	var keys []string
+	for key, e := range st.snapshot() {
+		if pattern != "" && !matchGlob(pattern, key) {
+			continue
+		}
+		e.rwm.RLock()
+		if !e.dead && len(e.m) > 0 && !e.expired(now) {
+			keys = append(keys, key)
+		}
+		e.rwm.RUnlock()
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (st *Store) snapshot() map[string]*storeEntry {
+	st.rwm.RLock()
+	defer st.rwm.RUnlock()
+	entries := make(map[string]*storeEntry, len(st.keys))
+	for key, e := range st.keys {
+		entries[key] = e
+	}
+	return entries
+}
+
+// Expire makes key expire after ttl, a ttl that is not positive deletes it.
+// It returns whether the key exists.
+func (st *Store) Expire(key string, ttl time.Duration) (ok bool) {
+	st.with(nil, []string{key}, func(entries map[string]*storeEntry) {
+		e := entries[key]
+		if ok = len(e.m) > 0; !ok {
+			return
+		}
+		if ttl <= 0 {
+			e.m = make(threadUnsafeSet)
+			return
+		}
+		e.expires = st.now().Add(ttl)
+	})
+	return ok
+}
+
+// Persist removes the expiry of key, and returns whether it had one.
+func (st *Store) Persist(key string) (ok bool) {
+	st.with(nil, []string{key}, func(entries map[string]*storeEntry) {
+		e := entries[key]
+		ok = len(e.m) > 0 && !e.expires.IsZero()
+		e.expires = time.Time{}
+	})
+	return ok
+}
+
+// TTL returns the time left before key expires, or -1 if it does not expire; ok is false if it is missing.
+func (st *Store) TTL(key string) (ttl time.Duration, ok bool) {
+	st.with([]string{key}, nil, func(entries map[string]*storeEntry) {
+		e, found := entries[key]
+		switch {
+		case !found || len(e.m) == 0:
+		case e.expires.IsZero():
+			ttl, ok = -1, true
+		default:
+			ttl, ok = e.expires.Sub(st.now()), true
+		}
+	})
+	return ttl, ok
+}
+
+// Sweep deletes the expired keys, and returns how many it deleted.
+// Expired keys are invisible anyway, Sweep only reclaims their memory.
+func (st *Store) Sweep() (deleted int) {
+	now := st.now()
+	for key, e := range st.snapshot() {
+		e.rwm.Lock()
+		if !e.dead && e.expired(now) {
+			st.remove(key, e)
+			deleted++
+		}
+		e.rwm.Unlock()
+	}
+	return deleted
+}
@@ -0,0 +1,286 @@
+package set
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	st := NewStore(nil)
+	if got := st.SAdd("a", 1, 2, 3, 3); got != 3 {
+		t.Errorf("SAdd() = %v, want %v", got, 3)
+	}
+	if got := st.SAdd("a", 3, 4); got != 1 {
+		t.Errorf("SAdd() = %v, want %v", got, 1)
+	}
+	if got := st.SRem("a", 4, 5); got != 1 {
+		t.Errorf("SRem() = %v, want %v", got, 1)
+	}
+	if !st.SIsMember("a", 1) || st.SIsMember("a", 4) || st.SIsMember("missing", 1) {
+		t.Errorf("SIsMember() = %v, want %v", st.SMembers("a"), "{1,2,3}")
+	}
+	if got := st.SMembers("a"); !got.Equal(NewSet(1, 2, 3)) || st.SCard("a") != 3 || st.SCard("missing") != 0 {
+		t.Errorf("SMembers() = %v, want %v", got, "{1,2,3}")
+	}
+	st.SMembers("a").Adds(9)
+	if st.SIsMember("a", 9) {
+		t.Errorf("SMembers() returned the stored set")
+	}
+	if st.SRem("a", 1, 2, 3); st.Exists("a") != 0 || len(st.Keys("")) != 0 {
+		t.Errorf("Exists() of an emptied key = %v, want %v", st.Exists("a"), 0)
+	}
+}
+
+func TestStore_Algebra(t *testing.T) {
+	st := NewStore(nil)
+	st.SAdd("a", 1, 2, 3)
+	st.SAdd("b", 2, 3, 4)
+	st.SAdd("c", 3, 9)
+	tests := []struct {
+		name string
+		got  ISet
+		want ISet
+	}{
+		{"SUnion", st.SUnion("a", "b", "missing"), NewSet(1, 2, 3, 4)},
+		{"SInter", st.SInter("a", "b", "c"), NewSet(3)},
+		{"SInter missing", st.SInter("a", "missing"), NewSet()},
+		{"SDiff", st.SDiff("a", "b"), NewSet(1)},
+		{"SDiff missing", st.SDiff("missing", "a"), NewSet()},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s() = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	st.Expire("d", time.Hour)
+	st.SAdd("d", 7)
+	st.Expire("d", time.Hour)
+	if n := st.SUnionStore("d", "a", "b"); n != 4 || !st.SMembers("d").Equal(NewSet(1, 2, 3, 4)) {
+		t.Errorf("SUnionStore() = %v, %v, want %v", n, st.SMembers("d"), "{1,2,3,4}")
+	}
+	if ttl, ok := st.TTL("d"); ttl != -1 || !ok {
+		t.Errorf("TTL() after SUnionStore() = %v, %v, want %v", ttl, ok, -1)
+	}
+	if n := st.SInterStore("a", "a", "c"); n != 1 || !st.SMembers("a").Equal(NewSet(3)) {
+		t.Errorf("SInterStore() = %v, %v, want %v", n, st.SMembers("a"), "{3}")
+	}
+	if n := st.SDiffStore("d", "c", "c"); n != 0 || st.Exists("d") != 0 {
+		t.Errorf("SDiffStore() = %v, %v, want %v", n, st.SMembers("d"), "{}")
+	}
+	if !st.SMove("b", "e", 4) || st.SMove("b", "e", 4) || !st.SIsMember("e", 4) || st.SIsMember("b", 4) {
+		t.Errorf("SMove() = %v, %v, want %v, %v", st.SMembers("b"), st.SMembers("e"), "{2,3}", "{4}")
+	}
+	if !st.SMove("e", "e", 4) || st.SMove("missing", "e", 4) || st.Exists("missing") != 0 {
+		t.Errorf("SMove() = %v, want %v", st.SMembers("e"), "{4}")
+	}
+	if st.Del("b", "e", "missing") != 2 || st.Exists("b", "e", "c", "c") != 2 {
+		t.Errorf("Del() = %v, want %v", st.Keys(""), []string{"a", "c"})
+	}
+}
+
+func TestStore_Random(t *testing.T) {
+	st := NewStore(nil)
+	st.SAdd("a", 1, 2, 3, 4, 5)
+	if got := st.SRandMemberN("a", 3); len(got) != 3 || !NewSet(got...).IsSub(st.SMembers("a")) || NewSet(got...).Cardinality() != 3 {
+		t.Errorf("SRandMemberN(3) = %v, want 3 distinct members", got)
+	}
+	if got := st.SRandMemberN("a", 10); len(got) != 5 {
+		t.Errorf("SRandMemberN(10) = %v, want every member", got)
+	}
+	if got := st.SRandMemberN("a", -10); len(got) != 10 || !NewSet(got...).IsSub(st.SMembers("a")) {
+		t.Errorf("SRandMemberN(-10) = %v, want 10 members", got)
+	}
+	if m, ok := st.SRandMember("a"); !ok || !st.SIsMember("a", m) || st.SCard("a") != 5 {
+		t.Errorf("SRandMember() = %v, %v, want a member", m, ok)
+	}
+	popped := st.SPopN("a", 2)
+	if m, ok := st.SPop("a"); ok {
+		popped = append(popped, m)
+	}
+	if len(popped) != 3 || st.SCard("a") != 2 || !NewSet(popped...).Intersections(st.SMembers("a")).Empty() {
+		t.Errorf("SPop() = %v, left %v, want 3 members popped", popped, st.SMembers("a"))
+	}
+	st.SPopN("a", 5)
+	if m, ok := st.SPop("a"); ok || st.Exists("a") != 0 {
+		t.Errorf("SPop() of a missing key = %v, %v, want %v, %v", m, ok, nil, false)
+	}
+	if m, ok := st.SRandMember("a"); ok || st.SRandMemberN("a", -3) != nil {
+		t.Errorf("SRandMember() of a missing key = %v, %v, want %v, %v", m, ok, nil, false)
+	}
+}
+
+func TestStore_SScan(t *testing.T) {
+	st := NewStore(nil)
+	for i := 0; i < 1000; i++ {
+		st.SAdd("a", fmt.Sprint("m", i))
+	}
+	seen := make(map[interface{}]int)
+	var cursor uint64
+	calls := 0
+	for {
+		var page []interface{}
+		cursor, page = st.SScan("a", cursor, "", 7)
+		calls++
+		for _, m := range page {
+			seen[m]++
+		}
+		// Concurrent mutations: members added and removed meanwhile may or may not be returned.
+		st.SAdd("a", fmt.Sprint("new", calls))
+		st.SRem("a", fmt.Sprint("m", 999-calls))
+		if cursor == 0 {
+			break
+		}
+	}
+	for i := 0; i < 1000-calls; i++ {
+		if seen[fmt.Sprint("m", i)] != 1 {
+			t.Fatalf("SScan() returned m%d %d times, want once", i, seen[fmt.Sprint("m", i)])
+		}
+	}
+	if calls < 1000/7 {
+		t.Errorf("SScan() took %d calls, want pages of about 7 members", calls)
+	}
+	_, page := st.SScan("a", 0, "m1?", 1000)
+	if len(page) != 10 {
+		t.Errorf("SScan() matching m1? = %v, want m10 to m19", page)
+	}
+	if next, page := st.SScan("missing", 0, "", 10); next != 0 || page != nil {
+		t.Errorf("SScan() of a missing key = %v, %v, want %v, %v", next, page, 0, nil)
+	}
+}
+
+func Test_matchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"", "", true},
+		{"*", "anything/at all", true},
+		{"user:*", "user:42", true},
+		{"user:*", "group:42", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[c-a]llo", "hbllo", true},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"*a*b", "xaxxb", true},
+		{"*a*b", "xaxxbc", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestStore_Expire(t *testing.T) {
+	now, set := fakeClock(0)
+	st := NewStore(now)
+	st.SAdd("a", 1)
+	st.SAdd("b", 2)
+	if st.Expire("missing", time.Second) || !st.Expire("a", 10*time.Second) {
+		t.Errorf("Expire() of a missing key = %v, want %v", true, false)
+	}
+	if ttl, ok := st.TTL("a"); ttl != 10*time.Second || !ok {
+		t.Errorf("TTL() = %v, %v, want %v", ttl, ok, 10*time.Second)
+	}
+	if ttl, ok := st.TTL("missing"); ok {
+		t.Errorf("TTL() of a missing key = %v, %v, want %v", ttl, ok, false)
+	}
+	set(int64(10 * time.Second))
+	if st.SIsMember("a", 1) || st.Exists("a") != 0 || !reflect.DeepEqual(st.Keys(""), []string{"b"}) {
+		t.Errorf("Keys() after expiry = %v, want %v", st.Keys(""), []string{"b"})
+	}
+	if st.SAdd("a", 2); !st.SMembers("a").Equal(NewSet(2)) {
+		t.Errorf("SAdd() to an expired key = %v, want %v", st.SMembers("a"), "{2}")
+	}
+	if ttl, _ := st.TTL("a"); ttl != -1 {
+		t.Errorf("TTL() of a recreated key = %v, want %v", ttl, -1)
+	}
+	st.Expire("a", time.Second)
+	if !st.Persist("a") || st.Persist("a") {
+		t.Errorf("Persist() = %v, want %v", false, true)
+	}
+	st.Expire("a", time.Second)
+	st.Expire("b", time.Hour)
+	set(int64(12 * time.Second))
+	if got := st.Sweep(); got != 1 || len(st.snapshot()) != 1 {
+		t.Errorf("Sweep() = %v, want %v", got, 1)
+	}
+	if st.Expire("b", 0); st.Exists("b") != 0 {
+		t.Errorf("Expire(0) = %v, want the key deleted", st.Keys(""))
+	}
+}
+
+func TestStore_Concurrent(t *testing.T) {
+	st := NewStore(nil)
+	keys := []string{"a", "b", "c", "d"}
+	for i := 0; i < 100; i++ {
+		st.SAdd(keys[i%len(keys)], i)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				src, dst := keys[(g+i)%len(keys)], keys[(g+2*i+1)%len(keys)]
+				if m, ok := st.SRandMember(src); ok {
+					st.SMove(src, dst, m)
+				}
+				st.SUnionStore("all", keys...)
+				st.SCard("all")
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := st.SUnionStore("all", keys...); n != 100 {
+		t.Errorf("SUnionStore() after concurrent SMove() = %v, want %v", n, 100)
+	}
+	total := 0
+	for _, key := range keys {
+		total += st.SCard(key)
+	}
+	if total != 100 {
+		t.Errorf("SCard() total after concurrent SMove() = %v, want %v", total, 100)
+	}
+}
+
+// TestStore_ConcurrentDelete races multi-key commands with the deletion of the sets they lock,
+// so Store.with often finds a dead entry and retries.
+func TestStore_ConcurrentDelete(t *testing.T) {
+	st := NewStore(nil)
+	keys := []string{"a", "b", "c", "d"}
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key, other := keys[(g+i)%len(keys)], keys[(g+3*i+1)%len(keys)]
+				switch i % 4 {
+				case 0:
+					st.SAdd(key, i%3)
+				case 1:
+					st.SRem(key, 0, 1, 2)
+				case 2:
+					st.SMove(key, other, i%3)
+				default:
+					st.SUnion(keys...)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := st.SUnionStore("all", keys...); n > 3 {
+		t.Errorf("SUnionStore() = %v, want at most %v", n, 3)
+	}
+}
@@ -1,6 +1,9 @@
 package set
 
-import "sync"
+import (
+	"fmt"
+	"sync"
+)
 
 func NewSet(elems ...interface{}) ISet {
 	s := &threadSafeSet{m: NewThreadUnsafeSet(elems...).(*threadUnsafeSet)}
@@ -8,8 +11,17 @@ func NewSet(elems ...interface{}) ISet {
 }
 
 type threadSafeSet struct {
-	rwm sync.RWMutex
-	m   *threadUnsafeSet
+	rwm    sync.RWMutex
+	m      *threadUnsafeSet
+	shared bool // m is referenced by a snapshot and must be copied before it is mutated
+}
+
+// own makes s.m private to s again after a Snapshot. The caller must hold the write lock.
+func (s *threadSafeSet) own() {
+	if s.shared {
+		s.m = s.m.Clone().(*threadUnsafeSet)
+		s.shared = false
+	}
 }
 
 func (s *threadSafeSet) Empty() bool {
@@ -35,12 +47,14 @@ func (s *threadSafeSet) ToSlice() ISlice {
 func (s *threadSafeSet) Adds(elems ...interface{}) bool {
 	s.rwm.Lock()
 	defer s.rwm.Unlock()
+	s.own()
 	return s.m.Adds(elems...)
 }
 
 func (s *threadSafeSet) Removes(elems ...interface{}) bool {
 	s.rwm.Lock()
 	defer s.rwm.Unlock()
+	s.own()
 	return s.m.Removes(elems...)
 }
 
@@ -92,7 +106,9 @@ func (s *threadSafeSet) Complements(others ...ISet) ISet {
 }
 
 func (s *threadSafeSet) Clear() {
-	s.Removes(s.ToSlice().Interface()...)
+	s.rwm.Lock()
+	defer s.rwm.Unlock()
+	s.m, s.shared = NewThreadUnsafeSet().(*threadUnsafeSet), false
 }
 
 func (s *threadSafeSet) Contains(elems ...interface{}) bool {
@@ -115,6 +131,7 @@ func (s *threadSafeSet) Equal(other ISet) bool {
 func (s *threadSafeSet) Pop() interface{} {
 	s.rwm.Lock()
 	defer s.rwm.Unlock()
+	s.own()
 	return s.m.Pop()
 }
 
@@ -123,3 +140,13 @@ func (s *threadSafeSet) String() string {
 	defer s.rwm.RUnlock()
 	return s.m.String()
 }
+
+func (s *threadSafeSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", s)
+}
+
+func (s *threadSafeSet) Hash() uint64 {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+	return s.m.Hash()
+}
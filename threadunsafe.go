@@ -2,7 +2,6 @@ package set
 
 import (
 	"fmt"
-	"strings"
 )
 
 func NewThreadUnsafeSet(elems ...interface{}) ISet {
@@ -137,9 +136,17 @@ func (s *threadUnsafeSet) Pop() interface{} {
 }
 
 func (s *threadUnsafeSet) String() string {
-	elems := make([]string, 0, s.Cardinality())
+	return formatString(s)
+}
+
+func (s *threadUnsafeSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewThreadUnsafeSet", s)
+}
+
+func (s *threadUnsafeSet) Hash() uint64 {
+	var h setHash
 	for elem := range *s {
-		elems = append(elems, fmt.Sprintf("%v", elem))
+		h.add(elem)
 	}
-	return "{" + strings.Join(elems, ",") + "}"
+	return h.sum64()
 }
@@ -0,0 +1,149 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrElemType is wrapped by the errors reporting elements of the wrong type for a typed set, see NewSetOf.
+var ErrElemType = errors.New("go-set: element of the wrong type")
+
+// TypeError reports an element rejected by a typed set.
+type TypeError struct {
+	// Index is the position of the element among the arguments.
+	Index int
+	// Type is the type of the element, nil for a nil element.
+	Type reflect.Type
+	// Want describes the accepted elements.
+	Want string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("go-set: element %d of type %v, want %s", e.Index, e.Type, e.Want)
+}
+
+func (e *TypeError) Unwrap() error {
+	return ErrElemType
+}
+
+// typeGuard describes the elements a typed set accepts: those of one type, or implementing
+// one interface type, or of one kind.
+type typeGuard struct {
+	typ  reflect.Type
+	kind reflect.Kind
+}
+
+func (g typeGuard) accepts(elem interface{}) bool {
+	t := reflect.TypeOf(elem)
+	switch {
+	case t == nil:
+		return false
+	case g.typ == nil:
+		return t.Kind() == g.kind
+	case g.typ.Kind() == reflect.Interface:
+		return t.Implements(g.typ)
+	default:
+		return t == g.typ
+	}
+}
+
+func (g typeGuard) check(elems []interface{}) error {
+	for i := 0; i < len(elems); i++ {
+		if !g.accepts(elems[i]) {
+			return &TypeError{Index: i, Type: reflect.TypeOf(elems[i]), Want: g.String()}
+		}
+	}
+	return nil
+}
+
+func (g typeGuard) String() string {
+	if g.typ == nil {
+		return "kind " + g.kind.String()
+	}
+	return g.typ.String()
+}
+
+// NewSetOf returns a thread safe set that only accepts elements of type t, or implementing t if it is
+// an interface type. It returns a *TypeError if one of elems has another type.
+// Adds panics with a *TypeError on elements of another type, use TryAdds to get the error instead.
+// The algebra returns typed sets too, and panics if given a set typed differently.
+// Examples:
+// s, _ := NewSetOf(reflect.TypeOf(0), 1, 2)
+// s.Adds(int64(3)) // panics: go-set: element 0 of type int64, want int
+func NewSetOf(t reflect.Type, elems ...interface{}) (ISet, error) {
+	return newTypedSet(typeGuard{typ: t}, elems)
+}
+
+// NewSetOfKind is like NewSetOf, but accepts any element of kind k, named types included.
+func NewSetOfKind(k reflect.Kind, elems ...interface{}) (ISet, error) {
+	return newTypedSet(typeGuard{kind: k}, elems)
+}
+
+func newTypedSet(g typeGuard, elems []interface{}) (ISet, error) {
+	if err := g.check(elems); err != nil {
+		return nil, err
+	}
+	return &typedSet{threadSafeSet: NewSet(elems...).(*threadSafeSet), guard: g}, nil
+}
+
+type typedSet struct {
+	*threadSafeSet
+	guard typeGuard
+}
+
+func (s *typedSet) checkElems(elems []interface{}, adding bool) error {
+	if err := CheckHashable(elems...); err != nil || !adding {
+		return err
+	}
+	return s.guard.check(elems)
+}
+
+func (s *typedSet) Adds(elems ...interface{}) bool {
+	if err := s.guard.check(elems); err != nil {
+		panic(err)
+	}
+	return s.threadSafeSet.Adds(elems...)
+}
+
+// checkOthers panics if one of others is typed differently.
+func (s *typedSet) checkOthers(op string, others []ISet) {
+	for _, other := range others {
+		if o, ok := other.(*typedSet); ok && o.guard != s.guard {
+			panic(fmt.Errorf("go-set: %s() err, set of %s combined with set of %s: %w", op, s.guard, o.guard, ErrElemType))
+		}
+	}
+}
+
+func (s *typedSet) Unions(others ...ISet) ISet {
+	s.checkOthers("Unions", others)
+	result := s.Clone()
+	for _, other := range others {
+		result.Adds(other.ToSlice().Interface()...)
+	}
+	return result
+}
+
+func (s *typedSet) Intersections(others ...ISet) ISet {
+	s.checkOthers("Intersections", others)
+	return &typedSet{threadSafeSet: s.threadSafeSet.Intersections(others...).(*threadSafeSet), guard: s.guard}
+}
+
+func (s *typedSet) Complements(others ...ISet) ISet {
+	s.checkOthers("Complements", others)
+	return &typedSet{threadSafeSet: s.threadSafeSet.Complements(others...).(*threadSafeSet), guard: s.guard}
+}
+
+func (s *typedSet) Clone() ISet {
+	return &typedSet{threadSafeSet: s.threadSafeSet.Clone().(*threadSafeSet), guard: s.guard}
+}
+
+// Restore rejects snapshots holding elements of the wrong type with a *TypeError.
+func (s *typedSet) Restore(snapshot ISet) error {
+	if snap, ok := snapshot.(*snapshotSet); ok {
+		if err := s.guard.check(snap.ToSlice().Interface()); err != nil {
+			return err
+		}
+	}
+	return s.threadSafeSet.Restore(snapshot)
+}
@@ -0,0 +1,107 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testID int
+
+func expectPanic(t *testing.T, name string, want error, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		err, _ := recover().(error)
+		if !errors.Is(err, want) {
+			t.Errorf("%s panic = %v, want %v", name, err, want)
+		}
+	}()
+	fn()
+}
+
+func TestNewSetOf(t *testing.T) {
+	intType := reflect.TypeOf(0)
+	tests := []struct {
+		name  string
+		new   func(elems ...interface{}) (ISet, error)
+		ok    []interface{}
+		wrong interface{}
+		want  string
+	}{
+		{
+			name:  "type",
+			new:   func(elems ...interface{}) (ISet, error) { return NewSetOf(intType, elems...) },
+			ok:    []interface{}{1, 2},
+			wrong: int64(3),
+			want:  "go-set: element 1 of type int64, want int",
+		},
+		{
+			name: "interface",
+			new: func(elems ...interface{}) (ISet, error) {
+				return NewSetOf(reflect.TypeOf((*fmt.Stringer)(nil)).Elem(), elems...)
+			},
+			ok:    []interface{}{reflect.Int, reflect.String},
+			wrong: "a",
+			want:  "go-set: element 1 of type string, want fmt.Stringer",
+		},
+		{
+			name:  "kind",
+			new:   func(elems ...interface{}) (ISet, error) { return NewSetOfKind(reflect.Int, elems...) },
+			ok:    []interface{}{1, testID(2)},
+			wrong: nil,
+			want:  "go-set: element 1 of type <nil>, want kind int",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := tt.new(tt.ok...)
+			if err != nil || !s.Contains(tt.ok...) {
+				t.Fatalf("new() = %v, %v, want %v", s, err, tt.ok)
+			}
+			var te *TypeError
+			if _, err := tt.new(tt.ok[0], tt.wrong); !errors.As(err, &te) || err.Error() != tt.want {
+				t.Errorf("new() error = %v, want %v", err, tt.want)
+			}
+			if ok, err := TryAdds(s, tt.ok[0], tt.wrong); ok || err == nil || err.Error() != tt.want {
+				t.Errorf("TryAdds() = %v, %v, want %v", ok, err, tt.want)
+			}
+			expectPanic(t, "Adds()", ErrElemType, func() { s.Adds(tt.wrong) })
+			if s.Cardinality() != len(tt.ok) {
+				t.Errorf("Cardinality() = %v, want %v", s.Cardinality(), len(tt.ok))
+			}
+		})
+	}
+}
+
+func TestTypedSet_Algebra(t *testing.T) {
+	ints, _ := NewSetOf(reflect.TypeOf(0), 1, 2, 3)
+	moreInts, _ := NewSetOf(reflect.TypeOf(0), 3, 4)
+	strs, _ := NewSetOf(reflect.TypeOf(""), "a")
+
+	u := ints.Unions(moreInts, NewSet(5))
+	if want := NewSet(1, 2, 3, 4, 5); !u.Equal(want) {
+		t.Errorf("Unions() = %v, want %v", u, want)
+	}
+	expectPanic(t, "Adds() on the result of Unions()", ErrElemType, func() { u.Adds("x") })
+	if got, want := ints.Intersections(moreInts), NewSet(3); !got.Equal(want) {
+		t.Errorf("Intersections() = %v, want %v", got, want)
+	}
+	c := ints.Complements(moreInts)
+	if want := NewSet(1, 2); !c.Equal(want) {
+		t.Errorf("Complements() = %v, want %v", c, want)
+	}
+	expectPanic(t, "Adds() on the result of Complements()", ErrElemType, func() { c.Adds(int8(1)) })
+	expectPanic(t, "Clone().Adds()", ErrElemType, func() { ints.Clone().Adds(1.5) })
+
+	expectPanic(t, "Unions()", ErrElemType, func() { ints.Unions(strs) })
+	expectPanic(t, "Intersections()", ErrElemType, func() { ints.Intersections(strs) })
+	expectPanic(t, "Complements()", ErrElemType, func() { ints.Complements(strs) })
+	expectPanic(t, "Unions() with an untyped set", ErrElemType, func() { ints.Unions(NewSet(int64(1))) })
+
+	snap := NewSet("x").(ISnapshotter).Snapshot()
+	if err := ints.(ISnapshotter).Restore(snap); !errors.Is(err, ErrElemType) {
+		t.Errorf("Restore() error = %v, want %v", err, ErrElemType)
+	}
+}
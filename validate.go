@@ -0,0 +1,127 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrUnhashable is wrapped by the errors reporting elements that cannot be stored in a set, see UnhashableError.
+var ErrUnhashable = errors.New("go-set: unhashable element")
+
+// UnhashableError reports an element that cannot be stored in a set because == cannot compare it:
+// a slice, a map, a function, or an array, struct or interface holding one.
+type UnhashableError struct {
+	// Index is the position of the element among the arguments.
+	Index int
+	// Type is the type of the element.
+	Type reflect.Type
+}
+
+func (e *UnhashableError) Error() string {
+	return fmt.Sprintf("go-set: element %d of type %s is unhashable", e.Index, e.Type)
+}
+
+func (e *UnhashableError) Unwrap() error {
+	return ErrUnhashable
+}
+
+// CheckHashable returns an *UnhashableError for the first element that cannot be stored in a set
+// built by NewSet or NewThreadUnsafeSet, or nil if they all can.
+func CheckHashable(elems ...interface{}) error {
+	for i := 0; i < len(elems); i++ {
+		if elems[i] != nil && !hashableValue(reflect.ValueOf(elems[i])) {
+			return &UnhashableError{Index: i, Type: reflect.TypeOf(elems[i])}
+		}
+	}
+	return nil
+}
+
+// hashableValue returns whether v can be a map key. Unlike reflect.Type.Comparable,
+// it checks the dynamic values held by interfaces.
+func hashableValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Func:
+		return false
+	case reflect.Interface:
+		return v.IsNil() || hashableValue(v.Elem())
+	case reflect.Array:
+		switch v.Type().Elem().Kind() {
+		case reflect.Interface, reflect.Array, reflect.Struct:
+			for i := 0; i < v.Len(); i++ {
+				if !hashableValue(v.Index(i)) {
+					return false
+				}
+			}
+			return true
+		}
+		return v.Type().Comparable()
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !hashableValue(v.Field(i)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// elemChecker is implemented by sets accepting other elements than NewSet does, such as hash sets,
+// and by the sets wrapping an ISet, which forward to it.
+type elemChecker interface {
+	// checkElems validates elems for the set. adding also checks what only Adds rejects,
+	// such as elements of another type in a typed set.
+	checkElems(elems []interface{}, adding bool) error
+}
+
+// checkElems validates elements for s, by default that they are hashable.
+func checkElems(s ISet, elems []interface{}, adding bool) error {
+	if c, ok := s.(elemChecker); ok {
+		return c.checkElems(elems, adding)
+	}
+	return CheckHashable(elems...)
+}
+
+// TryNewSet is the strict version of NewSet: it validates the elements up front,
+// and returns an *UnhashableError instead of panicking.
+func TryNewSet(elems ...interface{}) (ISet, error) {
+	if err := CheckHashable(elems...); err != nil {
+		return nil, err
+	}
+	return NewSet(elems...), nil
+}
+
+// TryNewThreadUnsafeSet is the strict version of NewThreadUnsafeSet, see TryNewSet.
+func TryNewThreadUnsafeSet(elems ...interface{}) (ISet, error) {
+	if err := CheckHashable(elems...); err != nil {
+		return nil, err
+	}
+	return NewThreadUnsafeSet(elems...), nil
+}
+
+// TryAdds is s.Adds(elems...) for untrusted elements: if one of them is unhashable, it returns
+// an *UnhashableError and leaves s unchanged instead of panicking.
+// It returns a *TypeError for elements a typed set rejects, see NewSetOf,
+// and a *NumberError for numbers a normalizing set rejects, see NewNormalizingSet.
+func TryAdds(s ISet, elems ...interface{}) (bool, error) {
+	if err := checkElems(s, elems, true); err != nil {
+		return false, err
+	}
+	return s.Adds(elems...), nil
+}
+
+// TryRemoves is s.Removes(elems...) for untrusted elements, see TryAdds.
+func TryRemoves(s ISet, elems ...interface{}) (bool, error) {
+	if err := checkElems(s, elems, false); err != nil {
+		return false, err
+	}
+	return s.Removes(elems...), nil
+}
+
+// TryContains is s.Contains(elems...) for untrusted elements, see TryAdds.
+func TryContains(s ISet, elems ...interface{}) (bool, error) {
+	if err := checkElems(s, elems, false); err != nil {
+		return false, err
+	}
+	return s.Contains(elems...), nil
+}
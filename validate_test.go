@@ -0,0 +1,84 @@
+package set
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckHashable(t *testing.T) {
+	type withSlice struct {
+		A []int
+	}
+	type withAny struct {
+		A interface{}
+	}
+	tests := []struct {
+		name  string
+		elems []interface{}
+		index int // -1 if hashable
+	}{
+		{name: "scalars", elems: []interface{}{1, "a", nil, 2.5, struct{}{}}, index: -1},
+		{name: "comparable struct", elems: []interface{}{withAny{A: 1}, [2]interface{}{1, "a"}}, index: -1},
+		{name: "slice", elems: []interface{}{1, []int{1}}, index: 1},
+		{name: "map", elems: []interface{}{map[int]int{}}, index: 0},
+		{name: "func", elems: []interface{}{"a", "b", func() {}}, index: 2},
+		{name: "struct with slice", elems: []interface{}{withSlice{}}, index: 0},
+		{name: "interface holding a slice", elems: []interface{}{1, withAny{A: []int{1}}}, index: 1},
+		{name: "array holding a map", elems: []interface{}{[1]interface{}{map[int]int{}}}, index: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckHashable(tt.elems...)
+			var ue *UnhashableError
+			switch {
+			case tt.index < 0 && err != nil:
+				t.Errorf("CheckHashable() error = %v, want nil", err)
+			case tt.index >= 0 && (!errors.As(err, &ue) || ue.Index != tt.index || !errors.Is(err, ErrUnhashable)):
+				t.Errorf("CheckHashable() error = %v, want index %v", err, tt.index)
+			}
+		})
+	}
+}
+
+func TestTryAdds(t *testing.T) {
+	if _, err := TryNewSet(1, []int{1}); err == nil || err.Error() != "go-set: element 1 of type []int is unhashable" {
+		t.Errorf("TryNewSet() error = %v", err)
+	}
+	if _, err := TryNewThreadUnsafeSet(map[int]int{}); err == nil {
+		t.Errorf("TryNewThreadUnsafeSet() error = %v, want an error", err)
+	}
+	s, err := TryNewSet(1, 2)
+	if err != nil {
+		t.Fatalf("TryNewSet() error = %v", err)
+	}
+	if ok, err := TryAdds(s, 3, []int{4}); ok || err == nil || s.Contains(3) {
+		t.Errorf("TryAdds() = %v, %v, want s unchanged", ok, err)
+	}
+	if ok, err := TryAdds(s, 3); !ok || err != nil {
+		t.Errorf("TryAdds() = %v, %v, want true", ok, err)
+	}
+	if ok, err := TryContains(s, 3, []int{4}); ok || err == nil {
+		t.Errorf("TryContains() = %v, %v, want an error", ok, err)
+	}
+	if ok, err := TryRemoves(s, 3, map[int]int{}); ok || err == nil || !s.Contains(3) {
+		t.Errorf("TryRemoves() = %v, %v, want s unchanged", ok, err)
+	}
+	if ok, err := TryRemoves(s, 3); !ok || err != nil {
+		t.Errorf("TryRemoves() = %v, %v, want true", ok, err)
+	}
+	// Sets with a Hasher accept any element.
+	if ok, err := TryAdds(NewHashSet(DeepHasher), []int{1}); !ok || err != nil {
+		t.Errorf("TryAdds() = %v, %v, want true", ok, err)
+	}
+	// Wrappers check elements the way the set they wrap does.
+	for _, w := range []ISet{NewJournaledSet(NewHashSet(DeepHasher), 10), NewVersionedSet(NewHashSet(DeepHasher))} {
+		if ok, err := TryAdds(w, []int{1}); !ok || err != nil || !w.Contains([]int{1}) {
+			t.Errorf("TryAdds() = %v, %v, want true", ok, err)
+		}
+	}
+	typed, _ := NewSetOfKind(reflect.Int, 1)
+	if ok, err := TryAdds(NewVersionedSet(NewJournaledSet(typed, 10)), "a"); ok || !errors.Is(err, ErrElemType) {
+		t.Errorf("TryAdds() = %v, %v, want %v", ok, err, ErrElemType)
+	}
+}
@@ -0,0 +1,314 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrCompacted is returned when asking a VersionedSet about a version older than its compacted history.
+var ErrCompacted = errors.New("go-set: VersionedSet err, version has been compacted")
+
+// Op is the kind of a recorded mutation.
+type Op int
+
+const (
+	// OpAdd records an element being added.
+	OpAdd Op = iota + 1
+	// OpRemove records an element being removed, by Removes, Clear or Pop.
+	OpRemove
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpAdd:
+		return "add"
+	case OpRemove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// Change is a single element added to or removed from a VersionedSet.
+type Change struct {
+	// Version is the version the mutation produced, all changes made by one call share it.
+	Version uint64
+	Op      Op
+	Elem    interface{}
+}
+
+// NewVersionedSet wraps s so that every mutation is recorded in a history.
+// Each call to Adds, Removes, Clear or Pop that changes the set produces a new version,
+// the state of s when it is wrapped is version 0.
+// s must only be mutated through the returned VersionedSet from then on.
+func NewVersionedSet(s ISet) *VersionedSet {
+	empty := s.Clone()
+	empty.Clear()
+	return &VersionedSet{s: s, base: s.Clone(), empty: empty}
+}
+
+// VersionedSet is a thread safe ISet that remembers its past states, for auditing and time-travel queries.
+// Only effective changes are recorded: adding an element already present or removing an absent one
+// leaves no trace and does not bump the version.
+// The set algebra methods return sets of the wrapped implementation, which are not versioned.
+type VersionedSet struct {
+	rwm         sync.RWMutex
+	s           ISet     // current state
+	base        ISet     // state as of baseVersion
+	empty       ISet     // empty set of the wrapped implementation, to compare elements the way it does
+	baseVersion uint64   // oldest version that can still be queried
+	log         []Change // changes after baseVersion, in version order
+	version     uint64
+}
+
+// Version returns the current version of the set.
+func (v *VersionedSet) Version() uint64 {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.version
+}
+
+// record appends changes as a new version. The caller must hold the write lock.
+func (v *VersionedSet) record(op Op, elems []interface{}) {
+	if len(elems) == 0 {
+		return
+	}
+	v.version++
+	for _, elem := range elems {
+		v.log = append(v.log, Change{Version: v.version, Op: op, Elem: elem})
+	}
+}
+
+// AsOf returns a copy of the set as it was at the given version.
+// Versions newer than the current one yield the current state.
+// Examples:
+// v := NewVersionedSet(NewSet(1)) // version 0
+// v.Adds(2)                       // version 1
+// v.Removes(1)                    // version 2
+// v.AsOf(1) return {1,2}, nil
+func (v *VersionedSet) AsOf(version uint64) (ISet, error) {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	if version < v.baseVersion {
+		return nil, ErrCompacted
+	}
+	n := v.since(version)
+	if n <= len(v.log)/2 {
+		// Closer to the present, undo the newer changes.
+		result := v.s.Clone()
+		for i := len(v.log) - 1; i >= len(v.log)-n; i-- {
+			if c := v.log[i]; c.Op == OpAdd {
+				result.Removes(c.Elem)
+			} else {
+				result.Adds(c.Elem)
+			}
+		}
+		return result, nil
+	}
+	result := v.base.Clone()
+	for _, c := range v.log[:len(v.log)-n] {
+		if c.Op == OpAdd {
+			result.Adds(c.Elem)
+		} else {
+			result.Removes(c.Elem)
+		}
+	}
+	return result, nil
+}
+
+// ContainsAt returns whether elem was in the set at the given version, comparing elements as the wrapped set does.
+func (v *VersionedSet) ContainsAt(elem interface{}, version uint64) (bool, error) {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	if version < v.baseVersion {
+		return false, ErrCompacted
+	}
+	probe := v.empty.Clone()
+	if _, err := TryAdds(probe, elem); err != nil {
+		return false, nil // the wrapped set rejects elem, it was never in it
+	}
+	for i := len(v.log) - 1 - v.since(version); i >= 0; i-- {
+		if c := v.log[i]; probe.Contains(c.Elem) {
+			return c.Op == OpAdd, nil
+		}
+	}
+	return v.base.Contains(elem), nil
+}
+
+// ChangesSince returns the changes made after the given version, oldest first.
+// Examples:
+// v := NewVersionedSet(NewSet())
+// v.Adds(1, 2)  // version 1
+// v.Removes(1) // version 2
+// v.ChangesSince(1) return [{2 remove 1}], nil
+func (v *VersionedSet) ChangesSince(version uint64) ([]Change, error) {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	if version < v.baseVersion {
+		return nil, ErrCompacted
+	}
+	n := v.since(version)
+	result := make([]Change, n)
+	copy(result, v.log[len(v.log)-n:])
+	return result, nil
+}
+
+// since returns how many changes at the end of the log are newer than version. The caller must hold the lock.
+func (v *VersionedSet) since(version uint64) int {
+	var n int
+	for n < len(v.log) && v.log[len(v.log)-1-n].Version > version {
+		n++
+	}
+	return n
+}
+
+// Compact discards the history up to and including the given version, which can no longer be queried afterwards.
+// Versions newer than the current one compact the whole history.
+func (v *VersionedSet) Compact(version uint64) {
+	v.rwm.Lock()
+	defer v.rwm.Unlock()
+	if version <= v.baseVersion {
+		return
+	}
+	if version > v.version {
+		version = v.version
+	}
+	n := len(v.log) - v.since(version)
+	for _, c := range v.log[:n] {
+		if c.Op == OpAdd {
+			v.base.Adds(c.Elem)
+		} else {
+			v.base.Removes(c.Elem)
+		}
+	}
+	v.log = append([]Change(nil), v.log[n:]...)
+	v.baseVersion = version
+}
+
+func (v *VersionedSet) Empty() bool {
+	return v.Cardinality() == 0
+}
+
+func (v *VersionedSet) Singleton() bool {
+	return v.Cardinality() == 1
+}
+
+func (v *VersionedSet) Cardinality() int {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.Cardinality()
+}
+
+func (v *VersionedSet) ToSlice() ISlice {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.ToSlice()
+}
+
+func (v *VersionedSet) checkElems(elems []interface{}, adding bool) error {
+	return checkElems(v.s, elems, adding)
+}
+
+func (v *VersionedSet) Adds(elems ...interface{}) bool {
+	v.rwm.Lock()
+	defer v.rwm.Unlock()
+	var added []interface{}
+	for i := 0; i < len(elems); i++ {
+		if v.s.Adds(elems[i]) {
+			added = append(added, elems[i])
+		}
+	}
+	v.record(OpAdd, added)
+	return len(added) == len(elems)
+}
+
+func (v *VersionedSet) Removes(elems ...interface{}) bool {
+	v.rwm.Lock()
+	defer v.rwm.Unlock()
+	var removed []interface{}
+	for i := 0; i < len(elems); i++ {
+		if v.s.Removes(elems[i]) {
+			removed = append(removed, elems[i])
+		}
+	}
+	v.record(OpRemove, removed)
+	return len(removed) == len(elems)
+}
+
+func (v *VersionedSet) IsSub(other ISet) bool {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.IsSub(other)
+}
+
+func (v *VersionedSet) Unions(others ...ISet) ISet {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.Unions(others...)
+}
+
+func (v *VersionedSet) Intersections(others ...ISet) ISet {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.Intersections(others...)
+}
+
+func (v *VersionedSet) Complements(others ...ISet) ISet {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.Complements(others...)
+}
+
+func (v *VersionedSet) Clear() {
+	v.rwm.Lock()
+	defer v.rwm.Unlock()
+	removed := v.s.ToSlice().Interface()
+	v.s.Clear()
+	v.record(OpRemove, removed)
+}
+
+func (v *VersionedSet) Contains(elems ...interface{}) bool {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.Contains(elems...)
+}
+
+// Clone returns an unversioned clone of the current state, using the wrapped implementation.
+func (v *VersionedSet) Clone() ISet {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.Clone()
+}
+
+func (v *VersionedSet) Equal(other ISet) bool {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.Equal(other)
+}
+
+func (v *VersionedSet) Pop() interface{} {
+	v.rwm.Lock()
+	defer v.rwm.Unlock()
+	n := v.s.Cardinality()
+	elem := v.s.Pop()
+	if v.s.Cardinality() < n {
+		v.record(OpRemove, []interface{}{elem})
+	}
+	return elem
+}
+
+func (v *VersionedSet) String() string {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.String()
+}
+
+func (v *VersionedSet) Format(f fmt.State, verb rune) {
+	formatSet(f, verb, "NewSet", v)
+}
+
+func (v *VersionedSet) Hash() uint64 {
+	v.rwm.RLock()
+	defer v.rwm.RUnlock()
+	return v.s.Hash()
+}
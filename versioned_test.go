@@ -0,0 +1,149 @@
+package set
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestVersionedSet() *VersionedSet {
+	v := NewVersionedSet(NewThreadUnsafeSet(1)) // 0: {1}
+	v.Adds(2, 3)                                // 1: {1,2,3}
+	v.Adds(3)                                   // no change
+	v.Removes(1)                                // 2: {2,3}
+	v.Clear()                                   // 3: {}
+	v.Adds(1)                                   // 4: {1}
+	return v
+}
+
+func TestVersionedSet_AsOf(t *testing.T) {
+	v := newTestVersionedSet()
+	tests := []struct {
+		name    string
+		version uint64
+		want    ISet
+	}{
+		{name: "0", version: 0, want: NewSet(1)},
+		{name: "1", version: 1, want: NewSet(1, 2, 3)},
+		{name: "2", version: 2, want: NewSet(2, 3)},
+		{name: "3", version: 3, want: NewSet()},
+		{name: "4", version: 4, want: NewSet(1)},
+		{name: "future", version: 9, want: NewSet(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.AsOf(tt.version)
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("AsOf() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+	if v.Version() != 4 {
+		t.Errorf("Version() = %v, want %v", v.Version(), 4)
+	}
+}
+
+func TestVersionedSet_ContainsAt(t *testing.T) {
+	v := newTestVersionedSet()
+	tests := []struct {
+		name    string
+		elem    interface{}
+		version uint64
+		want    bool
+	}{
+		{name: "1", elem: 1, version: 0, want: true},
+		{name: "2", elem: 2, version: 0, want: false},
+		{name: "3", elem: 2, version: 1, want: true},
+		{name: "4", elem: 1, version: 2, want: false},
+		{name: "5", elem: 3, version: 3, want: false},
+		{name: "6", elem: 1, version: 4, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := v.ContainsAt(tt.elem, tt.version); err != nil || got != tt.want {
+				t.Errorf("ContainsAt() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionedSet_ContainsAtWrapped(t *testing.T) {
+	v := NewVersionedSet(NewHashSet(DeepHasher, []int{1}))
+	v.Adds([]int{2})
+	v.Removes([]int{1})
+	if got, err := v.ContainsAt([]int{1}, v.Version()-1); err != nil || !got {
+		t.Errorf("ContainsAt() = %v, %v, want %v", got, err, true)
+	}
+	if got, err := v.ContainsAt([]int{1}, v.Version()); err != nil || got {
+		t.Errorf("ContainsAt() = %v, %v, want %v", got, err, false)
+	}
+	if got, err := v.ContainsAt([]int{2}, 0); err != nil || got {
+		t.Errorf("ContainsAt() = %v, %v, want %v", got, err, false)
+	}
+
+	n, _ := NewNormalizingSet(NumberRules{})
+	v = NewVersionedSet(n)
+	v.Adds(int64(1))
+	v.Removes(1.0)
+	if got, err := v.ContainsAt(1, 1); err != nil || !got {
+		t.Errorf("ContainsAt() = %v, %v, want %v", got, err, true)
+	}
+	if got, err := v.ContainsAt(uint8(1), 2); err != nil || got {
+		t.Errorf("ContainsAt() = %v, %v, want %v", got, err, false)
+	}
+}
+
+func TestVersionedSet_ChangesSince(t *testing.T) {
+	v := newTestVersionedSet()
+	got, err := v.ChangesSince(2)
+	if err != nil {
+		t.Fatalf("ChangesSince() error = %v", err)
+	}
+	// Clear removes the elements in the wrapped set's order.
+	want := NewSet(Change{3, OpRemove, 2}, Change{3, OpRemove, 3}, Change{4, OpAdd, 1})
+	gotSet := NewSet()
+	for _, c := range got {
+		gotSet.Adds(c)
+	}
+	if len(got) != 3 || !gotSet.Equal(want) {
+		t.Errorf("ChangesSince() = %v, want %v", got, want)
+	}
+	if got, _ := v.ChangesSince(4); len(got) != 0 {
+		t.Errorf("ChangesSince() = %v, want %v", got, nil)
+	}
+}
+
+func TestVersionedSet_Compact(t *testing.T) {
+	v := newTestVersionedSet()
+	v.Compact(2)
+	if _, err := v.AsOf(1); err != ErrCompacted {
+		t.Errorf("AsOf() error = %v, want %v", err, ErrCompacted)
+	}
+	if _, err := v.ContainsAt(1, 1); err != ErrCompacted {
+		t.Errorf("ContainsAt() error = %v, want %v", err, ErrCompacted)
+	}
+	if _, err := v.ChangesSince(1); err != ErrCompacted {
+		t.Errorf("ChangesSince() error = %v, want %v", err, ErrCompacted)
+	}
+	if got, err := v.AsOf(2); err != nil || !got.Equal(NewSet(2, 3)) {
+		t.Errorf("AsOf() = %v, %v, want %v", got, err, "{2,3}")
+	}
+	v.Compact(100)
+	if got, err := v.ChangesSince(4); err != nil || len(got) != 0 {
+		t.Errorf("ChangesSince() = %v, %v, want %v", got, err, nil)
+	}
+	if got, err := v.AsOf(4); err != nil || !got.Equal(NewSet(1)) {
+		t.Errorf("AsOf() = %v, %v, want %v", got, err, "{1}")
+	}
+}
+
+func TestVersionedSet_Pop(t *testing.T) {
+	v := NewVersionedSet(NewSet(1))
+	if elem := v.Pop(); elem != 1 {
+		t.Errorf("Pop() = %v, want %v", elem, 1)
+	}
+	v.Pop()
+	got, _ := v.ChangesSince(0)
+	if want := []Change{{1, OpRemove, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangesSince() = %v, want %v", got, want)
+	}
+}